@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/bytecode"
+)
+
+func disasmCmd(args []string) {
+	var progCode string
+
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	flags.StringVar(&progCode, "c", "", "Program to disassemble")
+	_ = flags.Parse(args)
+
+	prog, ok := openProgram(progCode, flags.Args())
+	if !ok {
+		flags.Usage()
+		return
+	}
+
+	fn, err := bytecode.Compile(mustParse(prog, false))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := bytecode.Disassemble(os.Stdout, fn); err != nil {
+		log.Fatalln(err)
+	}
+}
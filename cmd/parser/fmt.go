@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/format"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+)
+
+type fmtOptions struct {
+	write bool
+	diff  bool
+	list  bool
+}
+
+func fmtCmd(args []string) {
+	var progCode string
+	var opts fmtOptions
+
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.StringVar(&progCode, "c", "", "Program to format")
+	flags.BoolVar(&opts.write, "w", false, "Write the result to the source file instead of stdout")
+	flags.BoolVar(&opts.diff, "d", false, "Display diffs instead of rewriting files")
+	flags.BoolVar(&opts.list, "l", false, "List files whose formatting differs")
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: %s fmt [-w|-d|-l] [-c code] [files...]\n", os.Args[0])
+		fmt.Fprintln(out, "Without files the program is read from stdin.")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if progCode != "" {
+		if err := formatSource("<code>", []byte(progCode), os.Stdout, fmtOptions{}); err != nil {
+			reportFmtError(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := formatSource("<stdin>", src, os.Stdout, fmtOptions{}); err != nil {
+			reportFmtError(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		if err := formatFile(path, opts); err != nil {
+			reportFmtError(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func formatFile(path string, opts fmtOptions) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return formatSource(path, src, os.Stdout, opts)
+}
+
+// formatSource formats the program and, depending on the options, writes
+// the result to out, back to the file or reports the difference.
+func formatSource(path string, src []byte, out io.Writer, opts fmtOptions) error {
+	res, err := format.Source(string(src))
+	if err != nil {
+		return &fmtError{path: path, err: err}
+	}
+
+	if !opts.write && !opts.diff && !opts.list {
+		_, err := out.Write(res)
+		return err
+	}
+
+	if bytes.Equal(src, res) {
+		return nil
+	}
+
+	if opts.list {
+		if _, err := fmt.Fprintln(out, path); err != nil {
+			return err
+		}
+	}
+	if opts.write {
+		if err := os.WriteFile(path, res, 0o644); err != nil {
+			return err
+		}
+	}
+	if opts.diff {
+		return difflib.WriteUnifiedDiff(out, difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(src)),
+			B:        difflib.SplitLines(string(res)),
+			FromFile: path + ".orig",
+			ToFile:   path,
+			Context:  3,
+		})
+	}
+
+	return nil
+}
+
+// fmtError is the error of formatting the file, syntax errors are reported
+// one per line prefixed with the file name.
+type fmtError struct {
+	path string
+	err  error
+}
+
+func (e *fmtError) Error() string {
+	return e.path + ":" + e.err.Error()
+}
+
+func reportFmtError(err error) {
+	fmtErr, ok := err.(*fmtError)
+	if !ok {
+		log.Println(err)
+		return
+	}
+
+	errs, ok := fmtErr.err.(parser.ErrorList)
+	if !ok {
+		log.Println(err)
+		return
+	}
+	for _, e := range errs {
+		log.Printf("%s:%s", fmtErr.path, e)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/lsp"
+)
+
+func lspCmd(args []string) {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	// stdout belongs to the protocol, everything else goes to stderr
+	log.SetOutput(os.Stderr)
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		log.Fatalln(err)
+	}
+}
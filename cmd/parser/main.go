@@ -1,82 +1,148 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
 )
 
+// commands are the subcommands, running the binary without one of them
+// dumps the AST of the program.
+var commands = map[string]func(args []string){
+	"run":    runCmd,
+	"disasm": disasmCmd,
+	"fmt":    fmtCmd,
+	"lsp":    lspCmd,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	var progCode string
+	var keepComments bool
 
 	flag.StringVar(&progCode, "c", "", "Expression to parse")
+	flag.BoolVar(&keepComments, "comments", false, "Keep the comments and the doc comments of the declarations")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [run|disasm|fmt|lsp] [-c code] [-comments] [files...|-]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	if progCode == "" {
-		args := flag.Args()
-		if len(args) == 0 {
-			flag.Usage()
-			return
-		}
-
-		var err error
-		progCode, err = readFiles(args)
-		if err != nil {
-			log.Fatalln(err)
-		}
+	prog, ok := openProgram(progCode, flag.Args())
+	if !ok {
+		flag.Usage()
+		return
 	}
 
-	astTree, err := parse(progCode)
-	if err != nil {
-		log.Fatalln(err)
-	}
+	astTree := mustParse(prog, keepComments)
 
 	if err := dumpJSON(os.Stdout, astTree); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func readFiles(paths []string) (string, error) {
-	var buf bytes.Buffer
+// openProgram returns the reader of the code given with the -c flag or,
+// when it is empty, of the files one after another, "-" stands for stdin.
+// It returns false when there is no program.
+func openProgram(progCode string, paths []string) (io.Reader, bool) {
+	if progCode != "" {
+		return strings.NewReader(progCode), true
+	}
 
-	for _, fpath := range paths {
-		if err := readFile(fpath, &buf); err != nil {
-			return "", err
-		}
+	if len(paths) == 0 {
+		return nil, false
 	}
 
-	return buf.String(), nil
+	return &filesReader{paths: paths}, true
 }
 
-func readFile(fpath string, w io.Writer) error {
+// filesReader reads the files in order, opening each of them only when the
+// previous one is read to the end.
+type filesReader struct {
+	paths []string
+	file  io.ReadCloser
+}
+
+func (r *filesReader) Read(p []byte) (int, error) {
+	for {
+		if r.file == nil {
+			if len(r.paths) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.open(r.paths[0]); err != nil {
+				return 0, err
+			}
+			r.paths = r.paths[1:]
+		}
+
+		n, err := r.file.Read(p)
+		if errors.Is(err, io.EOF) {
+			if closeErr := r.file.Close(); closeErr != nil {
+				log.Printf("could not close file: %s", closeErr)
+			}
+			r.file = nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *filesReader) open(fpath string) error {
+	if fpath == "-" {
+		r.file = io.NopCloser(os.Stdin)
+		return nil
+	}
+
 	fp, err := os.Open(fpath)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if closeErr := fp.Close(); closeErr != nil {
-			log.Printf("could not close file: %s", closeErr)
+	r.file = fp
+	return nil
+}
+
+// mustParse parses the program and exits reporting every syntax error if
+// there are any.
+func mustParse(r io.Reader, keepComments bool) ast.Node {
+	astTree, errs := parse(r, keepComments)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			log.Println(err)
 		}
-	}()
+		os.Exit(1)
+	}
 
-	_, err = io.Copy(w, fp)
-	return err
+	return astTree
 }
 
-func parse(s string) (ast.Node, error) {
+func parse(r io.Reader, keepComments bool) (ast.Node, parser.ErrorList) {
 	var b ast.Builder
 
-	tok := tokenizer.NewTokenizer(tokenizer.DefaultRules, s)
+	tok := tokenizer.NewReaderScanner(r)
 	p := parser.NewParser(tok, b)
+	if keepComments {
+		p.KeepComments()
+	}
 
-	return p.Parse()
+	return p.ParseRecover()
 }
 
 func dumpJSON(w io.Writer, tree ast.Node) error {
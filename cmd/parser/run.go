@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/bytecode"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/interp"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/vm"
+)
+
+func runCmd(args []string) {
+	var progCode string
+	var useVM bool
+
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.StringVar(&progCode, "c", "", "Program to run")
+	flags.BoolVar(&useVM, "vm", false, "Compile the program to bytecode and run it on the VM")
+	_ = flags.Parse(args)
+
+	prog, ok := openProgram(progCode, flags.Args())
+	if !ok {
+		flags.Usage()
+		return
+	}
+
+	astTree := mustParse(prog, false)
+
+	run := runInterp
+	if useVM {
+		run = runVM
+	}
+	if err := run(astTree); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func runInterp(astTree ast.Node) error {
+	return interp.NewInterpreter(os.Stdout).Run(astTree)
+}
+
+func runVM(astTree ast.Node) error {
+	fn, err := bytecode.Compile(astTree)
+	if err != nil {
+		return err
+	}
+
+	return vm.NewVM(os.Stdout).Run(fn)
+}
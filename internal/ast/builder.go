@@ -1,5 +1,7 @@
 package ast
 
+import "strconv"
+
 type NodeType int
 
 const (
@@ -7,6 +9,11 @@ const (
 	StringLitType
 	BoolLitType
 	NullLitType
+	ArrayLitType
+	ObjectLitType
+	PropertyType
+	FuncExprType
+	ArrowFuncType
 	SuperCallType
 	ProgramType
 	ExprStmtType
@@ -15,7 +22,9 @@ const (
 	MemberExprType
 	BinaryExprType
 	LogicalExprType
+	ConditionalExprType
 	UnaryExprType
+	UpdateExprType
 	AssignExprType
 	SeqExprType
 	ThisExprType
@@ -28,9 +37,18 @@ const (
 	WhileStmtType
 	DoWhileStmtType
 	ForStmtType
+	BreakStmtType
+	ContinueStmtType
+	LabeledStmtType
+	TryStmtType
+	CatchClauseType
+	ThrowStmtType
+	SwitchStmtType
+	SwitchCaseType
 	FuncDeclType
 	ClassDeclType
 	ReturnStmtType
+	BadStmtType
 )
 
 var nodeTypeNames = [...]string{
@@ -38,6 +56,11 @@ var nodeTypeNames = [...]string{
 	"StringLitType",
 	"BoolLitType",
 	"NullLitType",
+	"ArrayLitType",
+	"ObjectLitType",
+	"PropertyType",
+	"FuncExprType",
+	"ArrowFuncType",
 	"SuperCallType",
 	"ProgramType",
 	"ExprStmtType",
@@ -46,7 +69,9 @@ var nodeTypeNames = [...]string{
 	"MemberExprType",
 	"BinaryExprType",
 	"LogicalExprType",
+	"ConditionalExprType",
 	"UnaryExprType",
+	"UpdateExprType",
 	"AssignExprType",
 	"SeqExprType",
 	"ThisExprType",
@@ -59,9 +84,18 @@ var nodeTypeNames = [...]string{
 	"WhileStmtType",
 	"DoWhileStmtType",
 	"ForStmtType",
+	"BreakStmtType",
+	"ContinueStmtType",
+	"LabeledStmtType",
+	"TryStmtType",
+	"CatchClauseType",
+	"ThrowStmtType",
+	"SwitchStmtType",
+	"SwitchCaseType",
 	"FuncDeclType",
 	"ClassDeclType",
 	"ReturnStmtType",
+	"BadStmtType",
 }
 
 func (n NodeType) String() string {
@@ -83,20 +117,44 @@ func (b Builder) Program(body ...Node) Node {
 	}
 }
 
+// StringLit is a shorthand for the double-quoted literal of the string.
 func (b Builder) StringLit(s string) Node {
+	return b.StringLitRaw(Quote(s), s)
+}
+
+func (b Builder) StringLitRaw(raw string, s string) Node {
 	return &concreteNode{
 		Type: StringLitType,
 		Fields: &StringLit{
 			Value: s,
+			Raw:   raw,
 		},
 	}
 }
 
+// NumericLit is a shorthand for the decimal integer literal.
 func (b Builder) NumericLit(n int) Node {
+	return b.IntLit(strconv.Itoa(n), int64(n))
+}
+
+func (b Builder) IntLit(raw string, n int64) Node {
 	return &concreteNode{
 		Type: NumericLitType,
 		Fields: &NumericLit{
-			Value: n,
+			Kind: IntNumeric,
+			Raw:  raw,
+			Int:  n,
+		},
+	}
+}
+
+func (b Builder) FloatLit(raw string, f float64) Node {
+	return &concreteNode{
+		Type: NumericLitType,
+		Fields: &NumericLit{
+			Kind:  FloatNumeric,
+			Raw:   raw,
+			Float: f,
 		},
 	}
 }
@@ -117,6 +175,37 @@ func (b Builder) NullLit() Node {
 	}
 }
 
+// ArrayLit builds the array literal, the nil elements are holes.
+func (b Builder) ArrayLit(elements ...Node) Node {
+	return &concreteNode{
+		Type: ArrayLitType,
+		Fields: &ArrayLit{
+			Elements: elements,
+		},
+	}
+}
+
+func (b Builder) ObjectLit(props ...Node) Node {
+	return &concreteNode{
+		Type: ObjectLitType,
+		Fields: &ObjectLit{
+			Props: props,
+		},
+	}
+}
+
+func (b Builder) Property(kind PropertyKind, computed bool, key Node, value Node) Node {
+	return &concreteNode{
+		Type: PropertyType,
+		Fields: &Property{
+			Kind:     kind,
+			Computed: computed,
+			Key:      key,
+			Value:    value,
+		},
+	}
+}
+
 func (b Builder) ExprStmt(expr Node) Node {
 	return &concreteNode{
 		Type: ExprStmtType,
@@ -163,6 +252,17 @@ func (b Builder) UnaryExpr(op UnaryOp, arg Node) Node {
 	}
 }
 
+func (b Builder) UpdateExpr(op UpdateOp, prefix bool, arg Node) Node {
+	return &concreteNode{
+		Type: UpdateExprType,
+		Fields: &UpdateExpr{
+			Op:     op,
+			Prefix: prefix,
+			Arg:    arg,
+		},
+	}
+}
+
 func (b Builder) LogicalExpr(op LogicalOp, left Node, right Node) Node {
 	return &concreteNode{
 		Type: LogicalExprType,
@@ -174,6 +274,17 @@ func (b Builder) LogicalExpr(op LogicalOp, left Node, right Node) Node {
 	}
 }
 
+func (b Builder) ConditionalExpr(cond Node, cons Node, alt Node) Node {
+	return &concreteNode{
+		Type: ConditionalExprType,
+		Fields: &ConditionalExpr{
+			Cond: cond,
+			Cons: cons,
+			Alt:  alt,
+		},
+	}
+}
+
 func (b Builder) AssignExpr(op AssignOp, left Node, right Node) Node {
 	return &concreteNode{
 		Type: AssignExprType,
@@ -265,6 +376,87 @@ func (b Builder) ForStmt(init Node, cond Node, step Node, body Node) Node {
 	}
 }
 
+// BreakStmt builds the break statement, the label is nil if there is none.
+func (b Builder) BreakStmt(label Node) Node {
+	return &concreteNode{
+		Type: BreakStmtType,
+		Fields: &BreakStmt{
+			Label: label,
+		},
+	}
+}
+
+// ContinueStmt builds the continue statement, the label is nil if there is
+// none.
+func (b Builder) ContinueStmt(label Node) Node {
+	return &concreteNode{
+		Type: ContinueStmtType,
+		Fields: &ContinueStmt{
+			Label: label,
+		},
+	}
+}
+
+func (b Builder) LabeledStmt(label Node, body Node) Node {
+	return &concreteNode{
+		Type: LabeledStmtType,
+		Fields: &LabeledStmt{
+			Label: label,
+			Body:  body,
+		},
+	}
+}
+
+func (b Builder) TryStmt(block Node, handler Node, finalizer Node) Node {
+	return &concreteNode{
+		Type: TryStmtType,
+		Fields: &TryStmt{
+			Block:     block,
+			Handler:   handler,
+			Finalizer: finalizer,
+		},
+	}
+}
+
+func (b Builder) CatchClause(param Node, body Node) Node {
+	return &concreteNode{
+		Type: CatchClauseType,
+		Fields: &CatchClause{
+			Param: param,
+			Body:  body,
+		},
+	}
+}
+
+func (b Builder) ThrowStmt(arg Node) Node {
+	return &concreteNode{
+		Type: ThrowStmtType,
+		Fields: &ThrowStmt{
+			Arg: arg,
+		},
+	}
+}
+
+func (b Builder) SwitchStmt(disc Node, cases ...Node) Node {
+	return &concreteNode{
+		Type: SwitchStmtType,
+		Fields: &SwitchStmt{
+			Disc:  disc,
+			Cases: cases,
+		},
+	}
+}
+
+func (b Builder) SwitchCase(test Node, body ...Node) Node {
+	return &concreteNode{
+		Type: SwitchCaseType,
+		Fields: &SwitchCase{
+			Test: test,
+			Body: body,
+		},
+	}
+}
+
 func (b Builder) FuncDecl(name Node, params []Node, body Node) Node {
 	return &concreteNode{
 		Type: FuncDeclType,
@@ -276,6 +468,27 @@ func (b Builder) FuncDecl(name Node, params []Node, body Node) Node {
 	}
 }
 
+func (b Builder) FuncExpr(name Node, params []Node, body Node) Node {
+	return &concreteNode{
+		Type: FuncExprType,
+		Fields: &FuncExpr{
+			Name:   name,
+			Params: params,
+			Body:   body,
+		},
+	}
+}
+
+func (b Builder) ArrowFunc(params []Node, body Node) Node {
+	return &concreteNode{
+		Type: ArrowFuncType,
+		Fields: &ArrowFunc{
+			Params: params,
+			Body:   body,
+		},
+	}
+}
+
 func (b Builder) ReturnStmt(arg Node) Node {
 	return &concreteNode{
 		Type: ReturnStmtType,
@@ -340,3 +553,10 @@ func (b Builder) ThisExpr() Node {
 		Fields: &ThisExpr{},
 	}
 }
+
+func (b Builder) BadStmt() Node {
+	return &concreteNode{
+		Type:   BadStmtType,
+		Fields: &BadStmt{},
+	}
+}
@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 )
 
 type Node *concreteNode
@@ -11,18 +12,51 @@ type Fields interface{}
 
 type concreteNode struct {
 	Type NodeType
+	Loc  Loc
 	Fields
 }
 
+// Position is a location in the source code. Offset is a 0-based byte offset,
+// Line and Column are 1-based, Column is counted in bytes.
+type Position struct {
+	Offset int `json:"-"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// IsValid reports whether the position is known. Nodes created by Builder
+// outside of the parser have no position.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Loc is a span of the source code, End points right after the last byte.
+type Loc struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+func (l Loc) IsValid() bool {
+	return l.Start.IsValid()
+}
+
 func (c *concreteNode) MarshalJSON() ([]byte, error) {
 	result := map[string]interface{}{
 		"type": c.Type.String(),
 	}
+	if c.Loc.IsValid() {
+		result["loc"] = c.Loc
+		result["range"] = [2]int{c.Loc.Start.Offset, c.Loc.End.Offset}
+	}
 	b, err := jsonMarshal(c.Fields)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(b, &result); err != nil {
+	// The numbers are decoded as they are written so that the int64 values
+	// keep their precision
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
 		return nil, err
 	}
 
@@ -40,14 +74,121 @@ func jsonMarshal(val interface{}) ([]byte, error) {
 
 type Program struct {
 	Body []Node `json:"body"`
+	// Comments are all the comments of the program in the source order,
+	// the parser collects them only when asked to.
+	Comments []*Comment `json:"comments,omitempty"`
+}
+
+// Comment is a // line comment or a /* block comment */, Text includes the
+// comment markers.
+type Comment struct {
+	Text string `json:"text"`
+	Loc  Loc    `json:"loc"`
+}
+
+// CommentGroup is a run of comments with no tokens and no empty lines
+// between them. The group ending right above the VarStmt, FuncDecl or
+// ClassDecl is its Doc, the parser sets it only when it keeps the comments.
+type CommentGroup struct {
+	List []*Comment `json:"list"`
 }
 
+// Text returns the text of the comments without the comment markers. The
+// single space after // is removed, the lines are trimmed on the right and
+// the empty lines around the text are dropped.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+
+	var lines []string
+	for _, c := range g.List {
+		var text string
+		if strings.HasPrefix(c.Text, "//") {
+			text = strings.TrimPrefix(c.Text[2:], " ")
+		} else {
+			text = c.Text[2 : len(c.Text)-2]
+		}
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// StringLit is a string literal, Value is the decoded string and Raw is the
+// literal as it is written in the source code, quotes included.
 type StringLit struct {
 	Value string `json:"value"`
+	Raw   string `json:"raw"`
 }
 
+// NumericLit is an integer or a floating point literal. Raw is the literal
+// as it is written in the source code, Int holds the value of the integer
+// literals and Float the value of the floating point ones. The kinds are
+// told apart in the tree only, the interpreter and the VM evaluate every
+// literal as Float64 and do the arithmetic on float64 numbers, e.g.
+// 9223372036854775807 + 1 is 9223372036854776000 and not an overflow.
 type NumericLit struct {
-	Value int `json:"value"`
+	Kind  NumericKind `json:"kind"`
+	Raw   string      `json:"raw"`
+	Int   int64       `json:"-"`
+	Float float64     `json:"-"`
+}
+
+// MarshalJSON encodes the value of the literal of either kind, zero
+// included, under the "value" key.
+func (n *NumericLit) MarshalJSON() ([]byte, error) {
+	var value interface{} = n.Int
+	if n.Kind == FloatNumeric {
+		value = n.Float
+	}
+
+	return json.Marshal(struct {
+		Kind  NumericKind `json:"kind"`
+		Raw   string      `json:"raw"`
+		Value interface{} `json:"value"`
+	}{n.Kind, n.Raw, value})
+}
+
+// Float64 returns the value of the literal of either kind.
+func (n *NumericLit) Float64() float64 {
+	if n.Kind == FloatNumeric {
+		return n.Float
+	}
+	return float64(n.Int)
+}
+
+type NumericKind int
+
+const (
+	IntNumeric NumericKind = iota
+	FloatNumeric
+)
+
+var numericKindStrings = [...]string{
+	"int",   // IntNumeric
+	"float", // FloatNumeric
+}
+
+func (k NumericKind) String() string {
+	if k >= 0 && int(k) < len(numericKindStrings) {
+		return numericKindStrings[k]
+	}
+
+	return numericKindStrings[IntNumeric]
+}
+
+func (k NumericKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
 }
 
 type BoolLit struct {
@@ -56,6 +197,53 @@ type BoolLit struct {
 
 type NullLit struct{}
 
+// ArrayLit is the [a, b, c] literal, the elements left out as in [a, , c]
+// are holes and are nil.
+type ArrayLit struct {
+	Elements []Node `json:"elements"`
+}
+
+type ObjectLit struct {
+	Props []Node `json:"props"`
+}
+
+// Property is a property of the object literal. The Key is an Identifier,
+// a StringLit or a NumericLit, or any expression if the key is Computed.
+// The Value of the shorthand property {x} is the Identifier equal to the
+// Key, the Value of the method is a FuncDecl named as the Key.
+type Property struct {
+	Kind     PropertyKind `json:"kind"`
+	Computed bool         `json:"computed"`
+	Key      Node         `json:"key"`
+	Value    Node         `json:"value"`
+}
+
+type PropertyKind int
+
+const (
+	InitProperty PropertyKind = iota
+	ShorthandProperty
+	MethodProperty
+)
+
+var propertyKindStrings = [...]string{
+	"init",      // InitProperty
+	"shorthand", // ShorthandProperty
+	"method",    // MethodProperty
+}
+
+func (k PropertyKind) String() string {
+	if k >= 0 && int(k) < len(propertyKindStrings) {
+		return propertyKindStrings[k]
+	}
+
+	return propertyKindStrings[InitProperty]
+}
+
+func (k PropertyKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
 type ExprStmt struct {
 	Expr Node `json:"expr"`
 }
@@ -81,31 +269,59 @@ const (
 	SubBinaryOp
 	MulBinaryOp
 	DivBinaryOp
+	ModBinaryOp
+	ExpBinaryOp
+
+	ShlBinaryOp
+	ShrBinaryOp
+	UShrBinaryOp
 
 	GtBinaryOp
 	LtBinaryOp
 	GteBinaryOp
 	LteBinaryOp
+	InstanceofBinaryOp
+	InBinaryOp
 
 	EqBinaryOp
 	NeqBinaryOp
+	StrictEqBinaryOp
+	StrictNeqBinaryOp
+
+	BitAndBinaryOp
+	BitOrBinaryOp
+	BitXorBinaryOp
 )
 
 var binaryOpStrings = [...]string{
 	"InavlidBinaryOp",
 
-	"+", // AddBinaryOp
-	"-", // SubBinaryOp
-	"*", // MulBinaryOp
-	"/", // DivBinaryOp
+	"+",  // AddBinaryOp
+	"-",  // SubBinaryOp
+	"*",  // MulBinaryOp
+	"/",  // DivBinaryOp
+	"%",  // ModBinaryOp
+	"**", // ExpBinaryOp
+
+	"<<",  // ShlBinaryOp
+	">>",  // ShrBinaryOp
+	">>>", // UShrBinaryOp
+
+	">",          // GtBinaryOp
+	"<",          // LtBinaryOp
+	">=",         // GteBinaryOp
+	"<=",         // LteBinaryOp
+	"instanceof", // InstanceofBinaryOp
+	"in",         // InBinaryOp
 
-	">",  // GtBinaryOp
-	"<",  // LtBinaryOp
-	">=", // GteBinaryOp
-	"<=", // LteBinaryOp
+	"==",  // EqBinaryOp
+	"!=",  // NeqBinaryOp
+	"===", // StrictEqBinaryOp
+	"!==", // StrictNeqBinaryOp
 
-	"==", // EqBinaryOp
-	"!=", // NeqBinaryOp
+	"&", // BitAndBinaryOp
+	"|", // BitOrBinaryOp
+	"^", // BitXorBinaryOp
 }
 
 func (b BinaryOp) String() string {
@@ -153,16 +369,32 @@ const (
 	SubAssignOp
 	MulAssignOp
 	DivAssignOp
+	ModAssignOp
+	ExpAssignOp
+	ShlAssignOp
+	ShrAssignOp
+	UShrAssignOp
+	BitAndAssignOp
+	BitOrAssignOp
+	BitXorAssignOp
 )
 
 var assignOpStrings = [...]string{
 	"InvalidAssignOp",
 
-	"=",  // SimpleAssignOp
-	"+=", // AddAssignOp
-	"-=", // SubAssignOp
-	"*=", // MulAssignOp
-	"/=", // DivAssignOp
+	"=",    // SimpleAssignOp
+	"+=",   // AddAssignOp
+	"-=",   // SubAssignOp
+	"*=",   // MulAssignOp
+	"/=",   // DivAssignOp
+	"%=",   // ModAssignOp
+	"**=",  // ExpAssignOp
+	"<<=",  // ShlAssignOp
+	">>=",  // ShrAssignOp
+	">>>=", // UShrAssignOp
+	"&=",   // BitAndAssignOp
+	"|=",   // BitOrAssignOp
+	"^=",   // BitXorAssignOp
 }
 
 func (a AssignOp) String() string {
@@ -209,6 +441,13 @@ type LogicalExpr struct {
 	Right Node      `json:"right"`
 }
 
+// ConditionalExpr is the cond ? cons : alt expression.
+type ConditionalExpr struct {
+	Cond Node `json:"cond"`
+	Cons Node `json:"cons"`
+	Alt  Node `json:"alt"`
+}
+
 type ThisExpr struct{}
 
 type LogicalOp int
@@ -268,13 +507,17 @@ const (
 
 	NotUnaryOp
 	NegUnaryOp
+	BitNotUnaryOp
+	TypeofUnaryOp
 )
 
 var unaryOpStrings = [...]string{
 	"InvalidUnaryOp",
 
-	"!", // NotUnaryOp
-	"-", // NegUnaryOp
+	"!",      // NotUnaryOp
+	"-",      // NegUnaryOp
+	"~",      // BitNotUnaryOp
+	"typeof", // TypeofUnaryOp
 }
 
 func (u UnaryOp) String() string {
@@ -306,12 +549,66 @@ func UnaryOpFromString(v string) UnaryOp {
 	return op
 }
 
+// UpdateExpr is an increment or a decrement of the argument, Prefix tells
+// ++x from x++.
+type UpdateExpr struct {
+	Op     UpdateOp `json:"op"`
+	Prefix bool     `json:"prefix"`
+	Arg    Node     `json:"arg"`
+}
+
+type UpdateOp int
+
+const (
+	InvalidUpdateOp UpdateOp = iota
+
+	IncUpdateOp
+	DecUpdateOp
+)
+
+var updateOpStrings = [...]string{
+	"InvalidUpdateOp",
+
+	"++", // IncUpdateOp
+	"--", // DecUpdateOp
+}
+
+func (u UpdateOp) String() string {
+	if u >= 0 && int(u) < len(updateOpStrings) {
+		return updateOpStrings[u]
+	}
+
+	return updateOpStrings[InvalidUpdateOp]
+}
+
+func (u UpdateOp) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+var updateOpMap = func() map[string]UpdateOp {
+	result := map[string]UpdateOp{}
+	for i, v := range updateOpStrings {
+		result[v] = UpdateOp(i)
+	}
+
+	return result
+}()
+
+func UpdateOpFromString(v string) UpdateOp {
+	op, ok := updateOpMap[v]
+	if !ok {
+		return InvalidUpdateOp
+	}
+	return op
+}
+
 type Identifier struct {
 	Name string `json:"name"`
 }
 
 type VarStmt struct {
-	Decls []Node `json:"decls"`
+	Decls []Node        `json:"decls"`
+	Doc   *CommentGroup `json:"doc,omitempty"`
 }
 
 type VarDecl struct {
@@ -342,12 +639,82 @@ type ForStmt struct {
 	Body Node `json:"body"`
 }
 
+// BreakStmt exits the innermost loop or the statement with the Label, the
+// Label is nil if there is none.
+type BreakStmt struct {
+	Label Node `json:"label"`
+}
+
+// ContinueStmt starts the next iteration of the innermost loop or the loop
+// with the Label, the Label is nil if there is none.
+type ContinueStmt struct {
+	Label Node `json:"label"`
+}
+
+// LabeledStmt is the statement with the label the break and continue
+// statements in its Body may refer to.
+type LabeledStmt struct {
+	Label Node `json:"label"`
+	Body  Node `json:"body"`
+}
+
+// TryStmt runs the Block and the Handler if the Block throws, the
+// Finalizer runs after them however they are left. Either the Handler or
+// the Finalizer is nil, but not both.
+type TryStmt struct {
+	Block     Node `json:"block"`
+	Handler   Node `json:"handler"`
+	Finalizer Node `json:"finalizer"`
+}
+
+// CatchClause is the catch (e) { } part of the TryStmt, the Param is bound
+// to the thrown value in the Body.
+type CatchClause struct {
+	Param Node `json:"param"`
+	Body  Node `json:"body"`
+}
+
+type ThrowStmt struct {
+	Arg Node `json:"arg"`
+}
+
+// SwitchStmt runs the Cases starting from the first one whose Test equals
+// the Disc, or from the default one if there is none. The Cases fall
+// through to the next ones up to the break statement.
+type SwitchStmt struct {
+	Disc  Node   `json:"disc"`
+	Cases []Node `json:"cases"`
+}
+
+// SwitchCase is the case clause of the SwitchStmt, the Test is nil for the
+// default clause.
+type SwitchCase struct {
+	Test Node   `json:"test"`
+	Body []Node `json:"body"`
+}
+
 type FuncDecl struct {
+	Name   Node          `json:"name"`
+	Params []Node        `json:"params"`
+	Body   Node          `json:"body"`
+	Doc    *CommentGroup `json:"doc,omitempty"`
+}
+
+// FuncExpr is the function defined in an expression, the Name is nil for
+// the anonymous ones. The name is visible in the body of the function only.
+type FuncExpr struct {
 	Name   Node   `json:"name"`
 	Params []Node `json:"params"`
 	Body   Node   `json:"body"`
 }
 
+// ArrowFunc is the (a, b) => a + b function, the Body is either a BlockStmt
+// or the expression the function returns.
+type ArrowFunc struct {
+	Params []Node `json:"params"`
+	Body   Node   `json:"body"`
+}
+
 type ReturnStmt struct {
 	Arg Node `json:"arg"`
 }
@@ -364,9 +731,14 @@ type CallExpr struct {
 }
 
 type ClassDecl struct {
-	ID    Node `json:"id"`
-	Super Node `json:"super"`
-	Body  Node `json:"body"`
+	ID    Node          `json:"id"`
+	Super Node          `json:"super"`
+	Body  Node          `json:"body"`
+	Doc   *CommentGroup `json:"doc,omitempty"`
 }
 
 type SuperCall struct{}
+
+// BadStmt is a placeholder for a statement containing syntax errors, it
+// spans the source code skipped by the parser while recovering.
+type BadStmt struct{}
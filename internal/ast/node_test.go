@@ -0,0 +1,30 @@
+package ast
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_MarshalJSON_NumericLit(t *testing.T) {
+	b := Builder{}
+	tests := []struct {
+		node Node
+		want string
+	}{
+		{b.NumericLit(0), `{"kind":"int","raw":"0","type":"NumericLitType","value":0}`},
+		{b.IntLit("0x7fffffffffffffff", 1<<63-1), `{"kind":"int","raw":"0x7fffffffffffffff","type":"NumericLitType","value":9223372036854775807}`},
+		{b.FloatLit("0.0", 0), `{"kind":"float","raw":"0.0","type":"NumericLitType","value":0}`},
+		{b.FloatLit("1.5e3", 1500), `{"kind":"float","raw":"1.5e3","type":"NumericLitType","value":1500}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			got, err := json.Marshal(tc.node)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
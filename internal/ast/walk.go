@@ -0,0 +1,198 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.Fields.(type) {
+	case *NumericLit, *StringLit, *BoolLit, *NullLit, *Identifier,
+		*EmptyStmt, *ThisExpr, *SuperCall, *BadStmt:
+		// nothing to do
+
+	case *ArrayLit:
+		for _, el := range n.Elements {
+			walkOpt(v, el)
+		}
+	case *ObjectLit:
+		walkList(v, n.Props)
+	case *Property:
+		Walk(v, n.Key)
+		Walk(v, n.Value)
+
+	case *Program:
+		walkList(v, n.Body)
+	case *ExprStmt:
+		Walk(v, n.Expr)
+	case *BlockStmt:
+		walkList(v, n.Body)
+	case *VarStmt:
+		walkList(v, n.Decls)
+	case *VarDecl:
+		Walk(v, n.ID)
+		walkOpt(v, n.Init)
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Cons)
+		walkOpt(v, n.Alt)
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *DoWhileStmt:
+		Walk(v, n.Body)
+		Walk(v, n.Cond)
+	case *ForStmt:
+		walkOpt(v, n.Init)
+		walkOpt(v, n.Cond)
+		walkOpt(v, n.Step)
+		Walk(v, n.Body)
+	case *BreakStmt:
+		walkOpt(v, n.Label)
+	case *ContinueStmt:
+		walkOpt(v, n.Label)
+	case *LabeledStmt:
+		Walk(v, n.Label)
+		Walk(v, n.Body)
+	case *TryStmt:
+		Walk(v, n.Block)
+		walkOpt(v, n.Handler)
+		walkOpt(v, n.Finalizer)
+	case *CatchClause:
+		Walk(v, n.Param)
+		Walk(v, n.Body)
+	case *ThrowStmt:
+		Walk(v, n.Arg)
+	case *SwitchStmt:
+		Walk(v, n.Disc)
+		walkList(v, n.Cases)
+	case *SwitchCase:
+		walkOpt(v, n.Test)
+		walkList(v, n.Body)
+	case *FuncDecl:
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+		Walk(v, n.Body)
+	case *FuncExpr:
+		walkOpt(v, n.Name)
+		walkList(v, n.Params)
+		Walk(v, n.Body)
+	case *ArrowFunc:
+		walkList(v, n.Params)
+		Walk(v, n.Body)
+	case *ReturnStmt:
+		walkOpt(v, n.Arg)
+	case *ClassDecl:
+		Walk(v, n.ID)
+		walkOpt(v, n.Super)
+		Walk(v, n.Body)
+
+	case *BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *LogicalExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *ConditionalExpr:
+		Walk(v, n.Cond)
+		Walk(v, n.Cons)
+		Walk(v, n.Alt)
+	case *UnaryExpr:
+		Walk(v, n.Arg)
+	case *UpdateExpr:
+		Walk(v, n.Arg)
+	case *AssignExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *SeqExpr:
+		walkList(v, n.Body)
+	case *MemberExpr:
+		Walk(v, n.Obj)
+		Walk(v, n.Prop)
+	case *CallExpr:
+		Walk(v, n.Callee)
+		walkList(v, n.Args)
+	case *NewExpr:
+		Walk(v, n.Callee)
+		walkList(v, n.Args)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %s", node.Type))
+	}
+
+	v.Visit(nil)
+}
+
+func walkList(v Visitor, list []Node) {
+	for _, n := range list {
+		Walk(v, n)
+	}
+}
+
+func walkOpt(v Visitor, n Node) {
+	if n != nil {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+type prePostVisitor struct {
+	pre  func(Node) bool
+	post func(Node)
+	// stack holds the nodes whose children are being visited
+	stack []Node
+}
+
+func (v *prePostVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		last := v.stack[len(v.stack)-1]
+		v.stack = v.stack[:len(v.stack)-1]
+		if v.post != nil {
+			v.post(last)
+		}
+		return nil
+	}
+
+	if v.pre != nil && !v.pre(node) {
+		return nil
+	}
+	v.stack = append(v.stack, node)
+	return v
+}
+
+// Traverse traverses the tree in depth-first order calling pre before the
+// children of the node are visited and post after that. If pre returns
+// false the children of the node are skipped and post is not called for
+// it. Either of the functions may be nil.
+func Traverse(node Node, pre func(Node) bool, post func(Node)) {
+	Walk(&prePostVisitor{pre: pre, post: post}, node)
+}
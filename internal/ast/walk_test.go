@@ -0,0 +1,180 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTree() Node {
+	b := Builder{}
+	// def f(a) { if (a) return a + 1; }
+	// f(2);
+	return b.Program(
+		b.FuncDecl(
+			b.Identifier("f"),
+			[]Node{b.Identifier("a")},
+			b.BlockStmt(
+				b.IfStmt(
+					b.Identifier("a"),
+					b.ReturnStmt(b.BinaryExpr(AddBinaryOp, b.Identifier("a"), b.NumericLit(1))),
+					nil,
+				),
+			),
+		),
+		b.ExprStmt(b.CallExpr(b.Identifier("f"), []Node{b.NumericLit(2)})),
+	)
+}
+
+func TestInspect(t *testing.T) {
+	var got []string
+	Inspect(testTree(), func(n Node) bool {
+		if n == nil {
+			got = append(got, "end")
+		} else {
+			got = append(got, n.Type.String())
+		}
+		return true
+	})
+
+	assert.Equal(t, []string{
+		"ProgramType",
+		"FuncDeclType",
+		"IdentifierType", "end",
+		"IdentifierType", "end",
+		"BlockStmtType",
+		"IfStmtType",
+		"IdentifierType", "end",
+		"ReturnStmtType",
+		"BinaryExprType",
+		"IdentifierType", "end",
+		"NumericLitType", "end",
+		"end", // BinaryExpr
+		"end", // ReturnStmt
+		"end", // IfStmt
+		"end", // BlockStmt
+		"end", // FuncDecl
+		"ExprStmtType",
+		"CallExprType",
+		"IdentifierType", "end",
+		"NumericLitType", "end",
+		"end", // CallExpr
+		"end", // ExprStmt
+		"end", // Program
+	}, got)
+}
+
+func TestInspect_Skip(t *testing.T) {
+	var got []string
+	Inspect(testTree(), func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		got = append(got, n.Type.String())
+		return n.Type != FuncDeclType
+	})
+
+	assert.Equal(t, []string{
+		"ProgramType",
+		"FuncDeclType",
+		"ExprStmtType",
+		"CallExprType",
+		"IdentifierType",
+		"NumericLitType",
+	}, got)
+}
+
+func TestTraverse(t *testing.T) {
+	var pre, post []string
+	Traverse(testTree(),
+		func(n Node) bool {
+			pre = append(pre, n.Type.String())
+			return n.Type != BlockStmtType
+		},
+		func(n Node) {
+			post = append(post, n.Type.String())
+		},
+	)
+
+	assert.Equal(t, []string{
+		"ProgramType",
+		"FuncDeclType",
+		"IdentifierType",
+		"IdentifierType",
+		"BlockStmtType",
+		"ExprStmtType",
+		"CallExprType",
+		"IdentifierType",
+		"NumericLitType",
+	}, pre)
+	assert.Equal(t, []string{
+		"IdentifierType",
+		"IdentifierType",
+		"FuncDeclType",
+		"IdentifierType",
+		"NumericLitType",
+		"CallExprType",
+		"ExprStmtType",
+		"ProgramType",
+	}, post)
+}
+
+func TestWalk_AllNodeTypes(t *testing.T) {
+	b := Builder{}
+	id := b.Identifier("x")
+	nodes := []Node{
+		b.Program(),
+		b.StringLit("s"),
+		b.NumericLit(1),
+		b.BoolLit(true),
+		b.NullLit(),
+		b.ArrayLit(id, nil),
+		b.ObjectLit(b.Property(InitProperty, false, id, id)),
+		b.ExprStmt(id),
+		b.BlockStmt(),
+		b.EmptyStmt(),
+		b.BinaryExpr(AddBinaryOp, id, id),
+		b.AssignExpr(SimpleAssignOp, id, id),
+		b.SeqExpr(id, id),
+		b.NewExpr(id, nil),
+		b.LogicalExpr(AndLogicalOp, id, id),
+		b.ConditionalExpr(id, id, id),
+		b.ThisExpr(),
+		b.UnaryExpr(NotUnaryOp, id),
+		b.UpdateExpr(IncUpdateOp, false, id),
+		id,
+		b.VarStmt(b.VarDecl(id, nil)),
+		b.IfStmt(id, b.EmptyStmt(), nil),
+		b.WhileStmt(id, b.EmptyStmt()),
+		b.DoWhileStmt(id, b.EmptyStmt()),
+		b.ForStmt(nil, nil, nil, b.EmptyStmt()),
+		b.LabeledStmt(id, b.BreakStmt(id)),
+		b.ContinueStmt(nil),
+		b.TryStmt(b.BlockStmt(), b.CatchClause(id, b.BlockStmt()), b.BlockStmt()),
+		b.ThrowStmt(id),
+		b.SwitchStmt(id, b.SwitchCase(id, b.EmptyStmt()), b.SwitchCase(nil)),
+		b.FuncDecl(id, nil, b.BlockStmt()),
+		b.ReturnStmt(nil),
+		b.FuncExpr(nil, nil, b.BlockStmt()),
+		b.ArrowFunc([]Node{id}, id),
+		b.MemberExpr(false, id, id),
+		b.CallExpr(id, nil),
+		b.ClassDecl(id, nil, b.BlockStmt()),
+		b.SuperCall(),
+		b.BadStmt(),
+	}
+
+	seen := map[NodeType]bool{}
+	for _, n := range nodes {
+		Inspect(n, func(n Node) bool {
+			if n != nil {
+				seen[n.Type] = true
+			}
+			return true
+		})
+	}
+
+	for typ := NodeType(0); int(typ) < len(nodeTypeNames); typ++ {
+		assert.True(t, seen[typ], "node type %s is not walked", typ)
+	}
+}
@@ -0,0 +1,39 @@
+package bytecode
+
+import "github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+
+// Chunk is the compiled code of a single function.
+type Chunk struct {
+	Code []byte
+	// Constants holds float64, string and *Function values
+	Constants []interface{}
+	// Pos holds the source position of every byte of Code
+	Pos []ast.Position
+}
+
+func (c *Chunk) write(pos ast.Position, b ...byte) {
+	c.Code = append(c.Code, b...)
+	for range b {
+		c.Pos = append(c.Pos, pos)
+	}
+}
+
+// ReadUint16 reads the 16 bit operand at the offset.
+func (c *Chunk) ReadUint16(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
+
+// Function is a compiled function prototype, the VM makes closures out of it.
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<function>"
+	}
+	return "<function " + f.Name + ">"
+}
@@ -0,0 +1,1463 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+const (
+	maxLocals        = 256
+	maxUpvalues      = 256
+	maxArgs          = 255
+	maxArrayElements = 1<<16 - 1
+	maxConstants     = 1 << 16
+	maxJump          = 1<<16 - 1
+)
+
+type funcKind int
+
+const (
+	scriptKind funcKind = iota
+	functionKind
+	methodKind
+)
+
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+type upvalue struct {
+	index   int
+	isLocal bool
+}
+
+// jumpTarget is the statement the break and continue statements jump out
+// of, it is either a loop, a switch or a labeled statement.
+type jumpTarget struct {
+	labels   []string
+	loop     bool
+	isSwitch bool
+	// locals and tries are the numbers of the locals and the try regions
+	// which outlive the jumps
+	locals int
+	tries  int
+	// breaks and continues are the jumps to the end of the statement and
+	// to the end of the loop body
+	breaks    []int
+	continues []int
+}
+
+// tryRegion is the part of the try statement its handler is installed for,
+// the jumps out of the region uninstall the handler and run the finalizer.
+type tryRegion struct {
+	// targets and locals are the numbers of the jump targets and the
+	// locals outside of the region, depth is the scope depth of the latter
+	targets int
+	locals  int
+	depth   int
+	// slot is the local keeping the value returned from the region while
+	// the finalizer runs, it is -1 if there is no finalizer
+	slot      int
+	finalizer ast.Node
+}
+
+type classInfo struct {
+	name     string
+	hasSuper bool
+}
+
+// compiler compiles a single function, nested functions are compiled by
+// the compilers chained through enclosing.
+type compiler struct {
+	enclosing *compiler
+	fn        *Function
+	kind      funcKind
+	class     *classInfo
+	locals    []local
+	upvalues  []upvalue
+	depth     int
+	constants map[interface{}]int
+	targets   []*jumpTarget
+	tries     []*tryRegion
+}
+
+// Compile compiles the program into the function the VM starts with.
+func Compile(program ast.Node) (*Function, error) {
+	prog, ok := program.Fields.(*ast.Program)
+	if !ok {
+		return nil, errorf(program, "expected program, got %s", program.Type)
+	}
+
+	c := newCompiler(nil, scriptKind, "<script>")
+	for _, stmt := range prog.Body {
+		if err := c.stmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	c.emit(program, OpNull)
+	c.emit(program, OpReturn)
+
+	return c.fn, nil
+}
+
+func newCompiler(enclosing *compiler, kind funcKind, name string) *compiler {
+	c := &compiler{
+		enclosing: enclosing,
+		fn:        &Function{Name: name},
+		kind:      kind,
+		constants: map[interface{}]int{},
+	}
+
+	// The first slot holds the function being called, for methods it holds
+	// the receiver.
+	slot0 := ""
+	if kind == methodKind {
+		slot0 = "this"
+	}
+	c.locals = append(c.locals, local{name: slot0})
+
+	return c
+}
+
+func (c *compiler) stmt(node ast.Node) error {
+	switch n := node.Fields.(type) {
+	case *ast.ExprStmt:
+		if err := c.expr(n.Expr); err != nil {
+			return err
+		}
+		c.emit(node, OpPop)
+		return nil
+	case *ast.EmptyStmt:
+		return nil
+	case *ast.BlockStmt:
+		c.beginScope()
+		if err := c.stmtList(n.Body); err != nil {
+			return err
+		}
+		c.endScope(node)
+		return nil
+	case *ast.VarStmt:
+		return c.varStmt(n)
+	case *ast.IfStmt:
+		return c.ifStmt(node, n)
+	case *ast.WhileStmt:
+		return c.whileStmt(node, n, nil)
+	case *ast.DoWhileStmt:
+		return c.doWhileStmt(node, n, nil)
+	case *ast.ForStmt:
+		return c.forStmt(node, n, nil)
+	case *ast.BreakStmt:
+		return c.breakStmt(node, n)
+	case *ast.ContinueStmt:
+		return c.continueStmt(node, n)
+	case *ast.LabeledStmt:
+		return c.labeledStmt(node)
+	case *ast.TryStmt:
+		return c.tryStmt(node, n)
+	case *ast.SwitchStmt:
+		return c.switchStmt(node, n)
+	case *ast.ThrowStmt:
+		if err := c.expr(n.Arg); err != nil {
+			return err
+		}
+		c.emit(node, OpThrow)
+		return nil
+	case *ast.FuncDecl:
+		return c.funcDecl(node, n)
+	case *ast.ReturnStmt:
+		return c.returnStmt(node, n)
+	case *ast.ClassDecl:
+		return c.classDecl(node, n)
+	case *ast.BadStmt:
+		return errorf(node, "cannot compile a statement with syntax errors")
+	default:
+		return errorf(node, "unexpected statement %s", node.Type)
+	}
+}
+
+func (c *compiler) stmtList(body []ast.Node) error {
+	for _, stmt := range body {
+		if err := c.stmt(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) varStmt(n *ast.VarStmt) error {
+	for _, d := range n.Decls {
+		decl := d.Fields.(*ast.VarDecl)
+
+		if decl.Init != nil {
+			if err := c.expr(decl.Init); err != nil {
+				return err
+			}
+		} else {
+			c.emit(d, OpNull)
+		}
+
+		if err := c.defineVariable(d, identName(decl.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) ifStmt(node ast.Node, n *ast.IfStmt) error {
+	if err := c.expr(n.Cond); err != nil {
+		return err
+	}
+
+	elseJump := c.emitJump(node, OpJumpIfFalse)
+	c.emit(node, OpPop)
+	if err := c.stmt(n.Cons); err != nil {
+		return err
+	}
+	endJump := c.emitJump(node, OpJump)
+
+	if err := c.patchJump(node, elseJump); err != nil {
+		return err
+	}
+	c.emit(node, OpPop)
+	if n.Alt != nil {
+		if err := c.stmt(n.Alt); err != nil {
+			return err
+		}
+	}
+
+	return c.patchJump(node, endJump)
+}
+
+// labeledStmt compiles the statement with all of its labels, the loop
+// gets the labels for the continue statements to refer to.
+func (c *compiler) labeledStmt(node ast.Node) error {
+	var labels []string
+	for {
+		n, ok := node.Fields.(*ast.LabeledStmt)
+		if !ok {
+			break
+		}
+		labels = append(labels, identName(n.Label))
+		node = n.Body
+	}
+
+	switch n := node.Fields.(type) {
+	case *ast.WhileStmt:
+		return c.whileStmt(node, n, labels)
+	case *ast.DoWhileStmt:
+		return c.doWhileStmt(node, n, labels)
+	case *ast.ForStmt:
+		return c.forStmt(node, n, labels)
+	}
+
+	c.pushTarget(labels, false)
+	if err := c.stmt(node); err != nil {
+		return err
+	}
+	return c.popTarget(node)
+}
+
+func (c *compiler) whileStmt(node ast.Node, n *ast.WhileStmt, labels []string) error {
+	loopStart := len(c.fn.Chunk.Code)
+	if err := c.expr(n.Cond); err != nil {
+		return err
+	}
+
+	exitJump := c.emitJump(node, OpJumpIfFalse)
+	c.emit(node, OpPop)
+	if err := c.loopBody(n.Body, labels); err != nil {
+		return err
+	}
+	if err := c.emitLoop(node, loopStart); err != nil {
+		return err
+	}
+
+	if err := c.patchJump(node, exitJump); err != nil {
+		return err
+	}
+	c.emit(node, OpPop)
+	return c.popTarget(node)
+}
+
+func (c *compiler) doWhileStmt(node ast.Node, n *ast.DoWhileStmt, labels []string) error {
+	loopStart := len(c.fn.Chunk.Code)
+	if err := c.loopBody(n.Body, labels); err != nil {
+		return err
+	}
+	if err := c.expr(n.Cond); err != nil {
+		return err
+	}
+
+	exitJump := c.emitJump(node, OpJumpIfFalse)
+	c.emit(node, OpPop)
+	if err := c.emitLoop(node, loopStart); err != nil {
+		return err
+	}
+
+	if err := c.patchJump(node, exitJump); err != nil {
+		return err
+	}
+	c.emit(node, OpPop)
+	return c.popTarget(node)
+}
+
+// loopBody compiles the body of the loop with the labels, the continue
+// statements jump to the end of it. The loop is left as the jump target
+// for the break statements, the caller pops it at the end of the loop.
+func (c *compiler) loopBody(body ast.Node, labels []string) error {
+	t := c.pushTarget(labels, true)
+	if err := c.stmt(body); err != nil {
+		return err
+	}
+	return c.patchJumps(body, t.continues)
+}
+
+func (c *compiler) breakStmt(node ast.Node, n *ast.BreakStmt) error {
+	t, err := c.findTarget(node, "break", n.Label)
+	if err != nil {
+		return err
+	}
+
+	if err := c.leave(node, t.tries, t.locals); err != nil {
+		return err
+	}
+	t.breaks = append(t.breaks, c.emitJump(node, OpJump))
+	return nil
+}
+
+func (c *compiler) continueStmt(node ast.Node, n *ast.ContinueStmt) error {
+	t, err := c.findTarget(node, "continue", n.Label)
+	if err != nil {
+		return err
+	}
+	if !t.loop {
+		return errorf(n.Label, "continue label %s does not denote a loop", identName(n.Label))
+	}
+
+	if err := c.leave(node, t.tries, t.locals); err != nil {
+		return err
+	}
+	t.continues = append(t.continues, c.emitJump(node, OpJump))
+	return nil
+}
+
+// findTarget returns the statement with the label or, if the label is nil,
+// the innermost loop or the innermost switch for the break statement.
+func (c *compiler) findTarget(node ast.Node, keyword string, label ast.Node) (*jumpTarget, error) {
+	for i := len(c.targets) - 1; i >= 0; i-- {
+		t := c.targets[i]
+		switch {
+		case label != nil:
+			if hasLabel(t.labels, identName(label)) {
+				return t, nil
+			}
+		case t.loop, t.isSwitch && keyword == "break":
+			return t, nil
+		}
+	}
+
+	switch {
+	case label != nil:
+		return nil, errorf(label, "undefined label: %s", identName(label))
+	case keyword == "break":
+		return nil, errorf(node, "break outside of loop or switch")
+	}
+	return nil, errorf(node, "%s outside of loop", keyword)
+}
+
+func (c *compiler) pushTarget(labels []string, loop bool) *jumpTarget {
+	t := &jumpTarget{labels: labels, loop: loop, locals: len(c.locals), tries: len(c.tries)}
+	c.targets = append(c.targets, t)
+	return t
+}
+
+// popTarget patches the break statements of the innermost jump target to
+// jump to the current position and drops the target.
+func (c *compiler) popTarget(node ast.Node) error {
+	t := c.targets[len(c.targets)-1]
+	c.targets = c.targets[:len(c.targets)-1]
+	return c.patchJumps(node, t.breaks)
+}
+
+// leave emits the code jumping out of the try regions and the scopes up
+// to the first tries regions and locals. The handlers of the regions are
+// uninstalled and their finalizers run in the scope of the try statement.
+func (c *compiler) leave(node ast.Node, tries, locals int) error {
+	top := len(c.locals)
+	for i := len(c.tries) - 1; i >= tries; i-- {
+		r := c.tries[i]
+		c.discardLocals(node, top, r.locals)
+		top = r.locals
+
+		c.emit(node, OpEndTry)
+		if r.finalizer != nil {
+			if err := c.inlineFinalizer(i); err != nil {
+				return err
+			}
+		}
+	}
+	c.discardLocals(node, top, locals)
+	return nil
+}
+
+// discardLocals drops the locals from the top one down to the first ones
+// off the stack before jumping out of their scope. The locals stay
+// declared as the code after the jump refers to them. The locals are
+// closed as they may be captured by a closure declared after the jump in a
+// loop.
+func (c *compiler) discardLocals(node ast.Node, top, locals int) {
+	for i := top; i > locals; i-- {
+		c.emit(node, OpCloseUpvalue)
+	}
+}
+
+// inlineFinalizer compiles the finalizer of the i-th try region for the
+// jump out of it. The finalizer sees neither the locals nor the jump
+// targets of the region.
+func (c *compiler) inlineFinalizer(i int) error {
+	r := c.tries[i]
+	locals, targets, tries, depth := c.locals, c.targets, c.tries, c.depth
+	c.locals = append([]local(nil), locals[:r.locals]...)
+	c.targets = append([]*jumpTarget(nil), targets[:r.targets]...)
+	c.tries = append([]*tryRegion(nil), tries[:i]...)
+	c.depth = r.depth
+
+	err := c.stmt(r.finalizer)
+	// The finalizer may have captured the locals of the enclosing scopes
+	for j := 0; j < r.locals; j++ {
+		locals[j].captured = locals[j].captured || c.locals[j].captured
+	}
+	c.locals, c.targets, c.tries, c.depth = locals, targets, tries, depth
+	return err
+}
+
+// switchStmt compiles the tests of the cases comparing them with the
+// discriminant kept in a local, followed by the bodies of the cases in the
+// source order so that they fall through. The default case is jumped to
+// when no test matches.
+func (c *compiler) switchStmt(node ast.Node, n *ast.SwitchStmt) error {
+	c.beginScope()
+	if err := c.expr(n.Disc); err != nil {
+		return err
+	}
+	if err := c.addLocal(node, ""); err != nil {
+		return err
+	}
+	disc := len(c.locals) - 1
+
+	bodyJumps := make([]int, len(n.Cases))
+	defaultCase := -1
+	for i, sc := range n.Cases {
+		test := sc.Fields.(*ast.SwitchCase).Test
+		if test == nil {
+			defaultCase = i
+			continue
+		}
+
+		c.emit(sc, OpGetLocal, byte(disc))
+		if err := c.expr(test); err != nil {
+			return err
+		}
+		c.emit(sc, OpEqual)
+		nextJump := c.emitJump(sc, OpJumpIfFalse)
+		c.emit(sc, OpPop)
+		bodyJumps[i] = c.emitJump(sc, OpJump)
+		if err := c.patchJump(sc, nextJump); err != nil {
+			return err
+		}
+		c.emit(sc, OpPop)
+	}
+	exitJump := c.emitJump(node, OpJump)
+
+	t := c.pushTarget(nil, false)
+	t.isSwitch = true
+	for i, sc := range n.Cases {
+		jump := bodyJumps[i]
+		if i == defaultCase {
+			jump, exitJump = exitJump, -1
+		}
+		if err := c.patchJump(sc, jump); err != nil {
+			return err
+		}
+
+		c.beginScope()
+		if err := c.stmtList(sc.Fields.(*ast.SwitchCase).Body); err != nil {
+			return err
+		}
+		c.endScope(sc)
+	}
+	if exitJump >= 0 {
+		if err := c.patchJump(node, exitJump); err != nil {
+			return err
+		}
+	}
+	if err := c.popTarget(node); err != nil {
+		return err
+	}
+
+	c.endScope(node)
+	return nil
+}
+
+// tryStmt compiles the try statement to the block guarded by the handler
+// followed by the catch clause and the code running the finalizer and
+// throwing the value further. The finalizer is compiled for every way out
+// of the statement. The scope of the statement holds the slot for the
+// value returned from it.
+func (c *compiler) tryStmt(node ast.Node, n *ast.TryStmt) error {
+	c.beginScope()
+	r := &tryRegion{targets: len(c.targets), depth: c.depth, slot: -1, finalizer: n.Finalizer}
+	if n.Finalizer != nil {
+		c.emit(node, OpNull)
+		if err := c.addLocal(node, ""); err != nil {
+			return err
+		}
+		r.slot = len(c.locals) - 1
+	}
+	r.locals = len(c.locals)
+
+	tryOp := OpTry
+	if n.Handler == nil {
+		tryOp = OpTryFinally
+	}
+	handlerJump := c.emitJump(node, tryOp)
+	c.beginScope()
+	if err := c.tryRegion(r, n.Block); err != nil {
+		return err
+	}
+	c.endScope(n.Block)
+	c.emit(node, OpEndTry)
+	if n.Finalizer != nil {
+		if err := c.stmt(n.Finalizer); err != nil {
+			return err
+		}
+	}
+	exitJumps := []int{c.emitJump(node, OpJump)}
+
+	// The handler starts with the thrown value on the top of the stack
+	if err := c.patchJump(node, handlerJump); err != nil {
+		return err
+	}
+	if n.Handler != nil {
+		jump, err := c.catchClause(n.Handler, r)
+		if err != nil {
+			return err
+		}
+		if jump >= 0 {
+			exitJumps = append(exitJumps, jump)
+		}
+	}
+
+	if n.Finalizer != nil {
+		// The wrapped thrown value is on the top of the stack, the one
+		// thrown by the handler is above its parameter
+		c.beginScope()
+		if n.Handler != nil {
+			if err := c.addLocal(node, ""); err != nil {
+				return err
+			}
+		}
+		if err := c.addLocal(node, ""); err != nil {
+			return err
+		}
+		if err := c.stmt(n.Finalizer); err != nil {
+			return err
+		}
+		c.emit(node, OpThrow)
+		c.locals = c.locals[:r.locals]
+		c.depth--
+	}
+
+	if err := c.patchJumps(node, exitJumps); err != nil {
+		return err
+	}
+	c.endScope(node)
+	return nil
+}
+
+// catchClause compiles the handler of the try statement. With the
+// finalizer the handler is guarded by the region of its own, the returned
+// jump leads to the end of the try statement then, otherwise it is -1.
+func (c *compiler) catchClause(node ast.Node, r *tryRegion) (int, error) {
+	n := node.Fields.(*ast.CatchClause)
+
+	c.beginScope()
+	if err := c.addLocal(n.Param, identName(n.Param)); err != nil {
+		return 0, err
+	}
+	if r.finalizer == nil {
+		if err := c.stmtList(n.Body.Fields.(*ast.BlockStmt).Body); err != nil {
+			return 0, err
+		}
+		c.endScope(node)
+		return -1, nil
+	}
+
+	handlerJump := c.emitJump(node, OpTryFinally)
+	if err := c.tryRegion(r, n.Body); err != nil {
+		return 0, err
+	}
+	c.emit(node, OpEndTry)
+	c.endScope(node)
+	if err := c.stmt(r.finalizer); err != nil {
+		return 0, err
+	}
+	exitJump := c.emitJump(node, OpJump)
+
+	return exitJump, c.patchJump(node, handlerJump)
+}
+
+// tryRegion compiles the statements of the block guarded by the handler
+// of the region in the current scope.
+func (c *compiler) tryRegion(r *tryRegion, block ast.Node) error {
+	c.tries = append(c.tries, r)
+	err := c.stmtList(block.Fields.(*ast.BlockStmt).Body)
+	c.tries = c.tries[:len(c.tries)-1]
+	return err
+}
+
+func (c *compiler) forStmt(node ast.Node, n *ast.ForStmt, labels []string) error {
+	c.beginScope()
+
+	if n.Init != nil {
+		if _, ok := n.Init.Fields.(*ast.VarStmt); ok {
+			if err := c.stmt(n.Init); err != nil {
+				return err
+			}
+		} else {
+			if err := c.expr(n.Init); err != nil {
+				return err
+			}
+			c.emit(node, OpPop)
+		}
+	}
+
+	loopStart := len(c.fn.Chunk.Code)
+	exitJump := -1
+	if n.Cond != nil {
+		if err := c.expr(n.Cond); err != nil {
+			return err
+		}
+		exitJump = c.emitJump(node, OpJumpIfFalse)
+		c.emit(node, OpPop)
+	}
+
+	if err := c.loopBody(n.Body, labels); err != nil {
+		return err
+	}
+	if n.Step != nil {
+		if err := c.expr(n.Step); err != nil {
+			return err
+		}
+		c.emit(node, OpPop)
+	}
+	if err := c.emitLoop(node, loopStart); err != nil {
+		return err
+	}
+
+	if exitJump >= 0 {
+		if err := c.patchJump(node, exitJump); err != nil {
+			return err
+		}
+		c.emit(node, OpPop)
+	}
+	if err := c.popTarget(node); err != nil {
+		return err
+	}
+
+	c.endScope(node)
+	return nil
+}
+
+func (c *compiler) funcDecl(node ast.Node, n *ast.FuncDecl) error {
+	name := identName(n.Name)
+
+	// Declare the local before compiling the body so that the function
+	// can refer to itself.
+	if c.depth > 0 {
+		if err := c.addLocal(node, name); err != nil {
+			return err
+		}
+	}
+
+	if err := c.function(node, name, n.Params, n.Body, functionKind, nil); err != nil {
+		return err
+	}
+
+	if c.depth == 0 {
+		return c.emitNamed(node, OpDefineGlobal, name)
+	}
+	return nil
+}
+
+// function compiles the body of the function and emits the closure for it,
+// the body is either a block statement or the expression of the arrow
+// function.
+func (c *compiler) function(node ast.Node, name string, params []ast.Node, body ast.Node, kind funcKind, class *classInfo) error {
+	fc := newCompiler(c, kind, name)
+	fc.class = class
+	if expr, ok := node.Fields.(*ast.FuncExpr); ok && expr.Name != nil {
+		// The name of the function expression is visible only inside of it
+		// and refers to the closure in the first slot.
+		fc.locals[0].name = name
+	}
+	fc.beginScope()
+
+	fc.fn.Arity = len(params)
+	for _, param := range params {
+		if err := fc.addLocal(param, identName(param)); err != nil {
+			return err
+		}
+	}
+
+	if block, ok := body.Fields.(*ast.BlockStmt); ok {
+		if err := fc.stmtList(block.Body); err != nil {
+			return err
+		}
+		fc.emit(body, OpNull)
+	} else if err := fc.expr(body); err != nil {
+		return err
+	}
+	fc.emit(body, OpReturn)
+
+	fc.fn.UpvalueCount = len(fc.upvalues)
+	idx, err := c.makeConstant(node, fc.fn)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node, OpClosure, uint16Operand(idx)...)
+	for _, uv := range fc.upvalues {
+		isLocal := byte(0)
+		if uv.isLocal {
+			isLocal = 1
+		}
+		c.fn.Chunk.write(node.Loc.Start, isLocal, byte(uv.index))
+	}
+
+	return nil
+}
+
+func (c *compiler) returnStmt(node ast.Node, n *ast.ReturnStmt) error {
+	if c.kind == scriptKind {
+		return errorf(node, "return outside of function")
+	}
+
+	if n.Arg != nil {
+		if err := c.expr(n.Arg); err != nil {
+			return err
+		}
+	} else {
+		c.emit(node, OpNull)
+	}
+
+	// The value is kept in the outermost try statement while the
+	// finalizers run
+	for _, r := range c.tries {
+		if r.finalizer == nil {
+			continue
+		}
+		c.emit(node, OpSetLocal, byte(r.slot))
+		c.emit(node, OpPop)
+		if err := c.leave(node, 0, len(c.locals)); err != nil {
+			return err
+		}
+		c.emit(node, OpGetLocal, byte(r.slot))
+		break
+	}
+
+	c.emit(node, OpReturn)
+	return nil
+}
+
+func (c *compiler) classDecl(node ast.Node, n *ast.ClassDecl) error {
+	class := &classInfo{
+		name:     identName(n.ID),
+		hasSuper: n.Super != nil,
+	}
+
+	if c.depth > 0 {
+		if err := c.addLocal(node, class.name); err != nil {
+			return err
+		}
+	}
+	if err := c.emitNamed(node, OpClass, class.name); err != nil {
+		return err
+	}
+	if c.depth == 0 {
+		if err := c.emitNamed(node, OpDefineGlobal, class.name); err != nil {
+			return err
+		}
+	}
+
+	if err := c.getVariable(n.ID, class.name); err != nil {
+		return err
+	}
+	if n.Super != nil {
+		if err := c.expr(n.Super); err != nil {
+			return err
+		}
+		c.emit(n.Super, OpInherit)
+	}
+
+	for _, member := range n.Body.Fields.(*ast.BlockStmt).Body {
+		decl, ok := member.Fields.(*ast.FuncDecl)
+		if !ok {
+			return errorf(member, "class body may only contain methods, got %s", member.Type)
+		}
+		if err := c.function(member, identName(decl.Name), decl.Params, decl.Body, methodKind, class); err != nil {
+			return err
+		}
+		if err := c.emitNamed(member, OpMethod, identName(decl.Name)); err != nil {
+			return err
+		}
+	}
+
+	c.emit(node, OpPop)
+	return nil
+}
+
+func (c *compiler) expr(node ast.Node) error {
+	switch n := node.Fields.(type) {
+	case *ast.NumericLit:
+		return c.emitConstant(node, n.Float64())
+	case *ast.StringLit:
+		return c.emitConstant(node, n.Value)
+	case *ast.BoolLit:
+		if n.Value {
+			c.emit(node, OpTrue)
+		} else {
+			c.emit(node, OpFalse)
+		}
+		return nil
+	case *ast.NullLit:
+		c.emit(node, OpNull)
+		return nil
+	case *ast.ArrayLit:
+		return c.arrayLit(node, n)
+	case *ast.ObjectLit:
+		return c.objectLit(node, n)
+	case *ast.FuncExpr:
+		name := ""
+		if n.Name != nil {
+			name = identName(n.Name)
+		}
+		return c.function(node, name, n.Params, n.Body, functionKind, nil)
+	case *ast.ArrowFunc:
+		return c.function(node, "", n.Params, n.Body, functionKind, nil)
+	case *ast.Identifier:
+		return c.getVariable(node, n.Name)
+	case *ast.ThisExpr:
+		if c.resolveLocal("this") < 0 && c.resolveUpvalue("this") < 0 {
+			return errorf(node, "'this' outside of method")
+		}
+		return c.getVariable(node, "this")
+	case *ast.SeqExpr:
+		for k, expr := range n.Body {
+			if k > 0 {
+				c.emit(node, OpPop)
+			}
+			if err := c.expr(expr); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *ast.UnaryExpr:
+		return c.unaryExpr(node, n)
+	case *ast.UpdateExpr:
+		return c.updateExpr(node, n)
+	case *ast.BinaryExpr:
+		if err := c.expr(n.Left); err != nil {
+			return err
+		}
+		if err := c.expr(n.Right); err != nil {
+			return err
+		}
+		return c.binaryOp(node, n.Op)
+	case *ast.LogicalExpr:
+		return c.logicalExpr(node, n)
+	case *ast.ConditionalExpr:
+		return c.conditionalExpr(node, n)
+	case *ast.AssignExpr:
+		return c.assignExpr(node, n)
+	case *ast.MemberExpr:
+		if err := c.expr(n.Obj); err != nil {
+			return err
+		}
+		if !n.Computed {
+			return c.emitNamed(node, OpGetProp, identName(n.Prop))
+		}
+		if err := c.expr(n.Prop); err != nil {
+			return err
+		}
+		c.emit(node, OpGetIndex)
+		return nil
+	case *ast.CallExpr:
+		return c.callExpr(node, n)
+	case *ast.NewExpr:
+		if err := c.expr(n.Callee); err != nil {
+			return err
+		}
+		return c.args(node, OpNew, n.Args)
+	default:
+		return errorf(node, "unexpected expression %s", node.Type)
+	}
+}
+
+func (c *compiler) arrayLit(node ast.Node, n *ast.ArrayLit) error {
+	if len(n.Elements) > maxArrayElements {
+		return errorf(node, "too many array elements")
+	}
+
+	for _, el := range n.Elements {
+		if el == nil {
+			c.emit(node, OpNull)
+			continue
+		}
+		if err := c.expr(el); err != nil {
+			return err
+		}
+	}
+	c.emit(node, OpArray, uint16Operand(len(n.Elements))...)
+	return nil
+}
+
+func (c *compiler) objectLit(node ast.Node, n *ast.ObjectLit) error {
+	c.emit(node, OpObject)
+
+	for _, member := range n.Props {
+		prop := member.Fields.(*ast.Property)
+		if prop.Kind == ast.MethodProperty {
+			decl := prop.Value.Fields.(*ast.FuncDecl)
+			if err := c.function(prop.Value, identName(decl.Name), decl.Params, decl.Body, methodKind, nil); err != nil {
+				return err
+			}
+			if err := c.emitNamed(member, OpDefineMethod, identName(decl.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name, ok := propName(prop); ok {
+			if err := c.expr(prop.Value); err != nil {
+				return err
+			}
+			if err := c.emitNamed(member, OpDefineProp, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The VM converts the computed and the numeric keys to strings
+		if err := c.expr(prop.Key); err != nil {
+			return err
+		}
+		if err := c.expr(prop.Value); err != nil {
+			return err
+		}
+		c.emit(member, OpDefineIndex)
+	}
+
+	return nil
+}
+
+// propName returns the name of the property known at compile time, it is
+// the identifier or the string key.
+func propName(prop *ast.Property) (string, bool) {
+	if prop.Computed {
+		return "", false
+	}
+
+	switch k := prop.Key.Fields.(type) {
+	case *ast.Identifier:
+		return k.Name, true
+	case *ast.StringLit:
+		return k.Value, true
+	default:
+		return "", false
+	}
+}
+
+func (c *compiler) unaryExpr(node ast.Node, n *ast.UnaryExpr) error {
+	if err := c.expr(n.Arg); err != nil {
+		return err
+	}
+
+	switch n.Op {
+	case ast.NotUnaryOp:
+		c.emit(node, OpNot)
+	case ast.NegUnaryOp:
+		c.emit(node, OpNeg)
+	case ast.BitNotUnaryOp:
+		c.emit(node, OpBitNot)
+	case ast.TypeofUnaryOp:
+		c.emit(node, OpTypeof)
+	default:
+		return errorf(node, "unknown unary operator %s", n.Op)
+	}
+	return nil
+}
+
+func (c *compiler) updateExpr(node ast.Node, n *ast.UpdateExpr) error {
+	op := OpInc
+	if n.Op == ast.DecUpdateOp {
+		op = OpDec
+	}
+
+	// update applies the operator to the current value of the target, the
+	// postfix form keeps the old value below the depth values the target
+	// needs to be set and drops the new one when it's done.
+	update := func(depth int) {
+		if !n.Prefix {
+			c.emit(node, OpTuck, byte(depth))
+		}
+		c.emit(node, op)
+	}
+
+	switch target := n.Arg.Fields.(type) {
+	case *ast.Identifier:
+		if err := c.getVariable(n.Arg, target.Name); err != nil {
+			return err
+		}
+		update(0)
+		if err := c.setVariable(n.Arg, target.Name); err != nil {
+			return err
+		}
+	case *ast.MemberExpr:
+		if err := c.expr(target.Obj); err != nil {
+			return err
+		}
+		if !target.Computed {
+			name := identName(target.Prop)
+			c.emit(n.Arg, OpDup)
+			if err := c.emitNamed(n.Arg, OpGetProp, name); err != nil {
+				return err
+			}
+			update(1)
+			if err := c.emitNamed(n.Arg, OpSetProp, name); err != nil {
+				return err
+			}
+			break
+		}
+		if err := c.expr(target.Prop); err != nil {
+			return err
+		}
+		c.emit(n.Arg, OpDup2)
+		c.emit(n.Arg, OpGetIndex)
+		update(2)
+		c.emit(n.Arg, OpSetIndex)
+	default:
+		return errorf(n.Arg, "invalid assignment target %s", n.Arg.Type)
+	}
+
+	if !n.Prefix {
+		c.emit(node, OpPop)
+	}
+	return nil
+}
+
+var binaryOps = map[ast.BinaryOp]Op{
+	ast.AddBinaryOp:        OpAdd,
+	ast.SubBinaryOp:        OpSub,
+	ast.MulBinaryOp:        OpMul,
+	ast.DivBinaryOp:        OpDiv,
+	ast.ModBinaryOp:        OpMod,
+	ast.ExpBinaryOp:        OpPow,
+	ast.ShlBinaryOp:        OpShl,
+	ast.ShrBinaryOp:        OpShr,
+	ast.UShrBinaryOp:       OpUShr,
+	ast.BitAndBinaryOp:     OpBitAnd,
+	ast.BitOrBinaryOp:      OpBitOr,
+	ast.BitXorBinaryOp:     OpBitXor,
+	ast.GtBinaryOp:         OpGreater,
+	ast.LtBinaryOp:         OpLess,
+	ast.GteBinaryOp:        OpGreaterEqual,
+	ast.LteBinaryOp:        OpLessEqual,
+	ast.InstanceofBinaryOp: OpInstanceof,
+	ast.InBinaryOp:         OpIn,
+	ast.EqBinaryOp:         OpEqual,
+	ast.NeqBinaryOp:        OpNotEqual,
+	// The values are never converted to be compared, so the strict
+	// equality operators agree with the plain ones.
+	ast.StrictEqBinaryOp:  OpEqual,
+	ast.StrictNeqBinaryOp: OpNotEqual,
+}
+
+func (c *compiler) binaryOp(node ast.Node, op ast.BinaryOp) error {
+	code, ok := binaryOps[op]
+	if !ok {
+		return errorf(node, "unknown binary operator %s", op)
+	}
+	c.emit(node, code)
+	return nil
+}
+
+func (c *compiler) conditionalExpr(node ast.Node, n *ast.ConditionalExpr) error {
+	if err := c.expr(n.Cond); err != nil {
+		return err
+	}
+
+	elseJump := c.emitJump(node, OpJumpIfFalse)
+	c.emit(node, OpPop)
+	if err := c.expr(n.Cons); err != nil {
+		return err
+	}
+	endJump := c.emitJump(node, OpJump)
+
+	if err := c.patchJump(node, elseJump); err != nil {
+		return err
+	}
+	c.emit(node, OpPop)
+	if err := c.expr(n.Alt); err != nil {
+		return err
+	}
+
+	return c.patchJump(node, endJump)
+}
+
+func (c *compiler) logicalExpr(node ast.Node, n *ast.LogicalExpr) error {
+	if err := c.expr(n.Left); err != nil {
+		return err
+	}
+
+	jumpOp := OpJumpIfFalse
+	if n.Op == ast.OrLogicalOp {
+		jumpOp = OpJumpIfTrue
+	}
+	endJump := c.emitJump(node, jumpOp)
+	c.emit(node, OpPop)
+	if err := c.expr(n.Right); err != nil {
+		return err
+	}
+
+	return c.patchJump(node, endJump)
+}
+
+// compoundAssignOps maps compound assignment operators to the binary
+// operators they apply.
+var compoundAssignOps = map[ast.AssignOp]ast.BinaryOp{
+	ast.AddAssignOp:    ast.AddBinaryOp,
+	ast.SubAssignOp:    ast.SubBinaryOp,
+	ast.MulAssignOp:    ast.MulBinaryOp,
+	ast.DivAssignOp:    ast.DivBinaryOp,
+	ast.ModAssignOp:    ast.ModBinaryOp,
+	ast.ExpAssignOp:    ast.ExpBinaryOp,
+	ast.ShlAssignOp:    ast.ShlBinaryOp,
+	ast.ShrAssignOp:    ast.ShrBinaryOp,
+	ast.UShrAssignOp:   ast.UShrBinaryOp,
+	ast.BitAndAssignOp: ast.BitAndBinaryOp,
+	ast.BitOrAssignOp:  ast.BitOrBinaryOp,
+	ast.BitXorAssignOp: ast.BitXorBinaryOp,
+}
+
+func (c *compiler) assignExpr(node ast.Node, n *ast.AssignExpr) error {
+	compound := n.Op != ast.SimpleAssignOp
+
+	// right compiles the right-hand side, for compound assignments the
+	// current value is already on the stack.
+	right := func() error {
+		if err := c.expr(n.Right); err != nil {
+			return err
+		}
+		if compound {
+			return c.binaryOp(node, compoundAssignOps[n.Op])
+		}
+		return nil
+	}
+
+	switch target := n.Left.Fields.(type) {
+	case *ast.Identifier:
+		if compound {
+			if err := c.getVariable(n.Left, target.Name); err != nil {
+				return err
+			}
+		}
+		if err := right(); err != nil {
+			return err
+		}
+		return c.setVariable(n.Left, target.Name)
+	case *ast.MemberExpr:
+		if err := c.expr(target.Obj); err != nil {
+			return err
+		}
+		if !target.Computed {
+			name := identName(target.Prop)
+			if compound {
+				c.emit(n.Left, OpDup)
+				if err := c.emitNamed(n.Left, OpGetProp, name); err != nil {
+					return err
+				}
+			}
+			if err := right(); err != nil {
+				return err
+			}
+			return c.emitNamed(n.Left, OpSetProp, name)
+		}
+		if err := c.expr(target.Prop); err != nil {
+			return err
+		}
+		if compound {
+			c.emit(n.Left, OpDup2)
+			c.emit(n.Left, OpGetIndex)
+		}
+		if err := right(); err != nil {
+			return err
+		}
+		c.emit(n.Left, OpSetIndex)
+		return nil
+	default:
+		return errorf(n.Left, "invalid assignment target %s", n.Left.Type)
+	}
+}
+
+func (c *compiler) callExpr(node ast.Node, n *ast.CallExpr) error {
+	if n.Callee.Type == ast.SuperCallType {
+		if c.kind != methodKind || c.class == nil {
+			return errorf(n.Callee, "'super' outside of method")
+		}
+		if !c.class.hasSuper {
+			return errorf(n.Callee, "class %s has no parent class", c.class.name)
+		}
+		if err := c.emitNamed(n.Callee, OpSuper, c.fn.Name); err != nil {
+			return err
+		}
+	} else if err := c.expr(n.Callee); err != nil {
+		return err
+	}
+
+	return c.args(node, OpCall, n.Args)
+}
+
+// args compiles the arguments followed by the call instruction.
+func (c *compiler) args(node ast.Node, op Op, args []ast.Node) error {
+	if len(args) > maxArgs {
+		return errorf(node, "too many arguments")
+	}
+
+	for _, arg := range args {
+		if err := c.expr(arg); err != nil {
+			return err
+		}
+	}
+	c.emit(node, op, byte(len(args)))
+	return nil
+}
+
+func (c *compiler) beginScope() {
+	c.depth++
+}
+
+// endScope drops the locals of the scope, the captured ones are moved to
+// the heap.
+func (c *compiler) endScope(node ast.Node) {
+	c.depth--
+
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.depth {
+		if c.locals[len(c.locals)-1].captured {
+			c.emit(node, OpCloseUpvalue)
+		} else {
+			c.emit(node, OpPop)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *compiler) addLocal(node ast.Node, name string) error {
+	if len(c.locals) >= maxLocals {
+		return errorf(node, "too many local variables in function")
+	}
+
+	c.locals = append(c.locals, local{name: name, depth: c.depth})
+	return nil
+}
+
+// defineVariable makes a variable of the value on the top of the stack.
+func (c *compiler) defineVariable(node ast.Node, name string) error {
+	if c.depth == 0 {
+		return c.emitNamed(node, OpDefineGlobal, name)
+	}
+	return c.addLocal(node, name)
+}
+
+func (c *compiler) getVariable(node ast.Node, name string) error {
+	if slot := c.resolveLocal(name); slot >= 0 {
+		c.emit(node, OpGetLocal, byte(slot))
+		return nil
+	}
+
+	idx := c.resolveUpvalue(name)
+	if idx >= maxUpvalues {
+		return errorf(node, "too many closure variables in function")
+	}
+	if idx >= 0 {
+		c.emit(node, OpGetUpvalue, byte(idx))
+		return nil
+	}
+
+	return c.emitNamed(node, OpGetGlobal, name)
+}
+
+func (c *compiler) setVariable(node ast.Node, name string) error {
+	if slot := c.resolveLocal(name); slot >= 0 {
+		c.emit(node, OpSetLocal, byte(slot))
+		return nil
+	}
+
+	idx := c.resolveUpvalue(name)
+	if idx >= maxUpvalues {
+		return errorf(node, "too many closure variables in function")
+	}
+	if idx >= 0 {
+		c.emit(node, OpSetUpvalue, byte(idx))
+		return nil
+	}
+
+	return c.emitNamed(node, OpSetGlobal, name)
+}
+
+func (c *compiler) resolveLocal(name string) int {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveUpvalue looks the variable up in the enclosing functions and
+// captures it, it returns -1 for globals.
+func (c *compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+
+	if slot := c.enclosing.resolveLocal(name); slot >= 0 {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(slot, true)
+	}
+
+	if idx := c.enclosing.resolveUpvalue(name); idx >= 0 {
+		return c.addUpvalue(idx, false)
+	}
+
+	return -1
+}
+
+func (c *compiler) addUpvalue(index int, isLocal bool) int {
+	for i, uv := range c.upvalues {
+		if uv.index == index && uv.isLocal == isLocal {
+			return i
+		}
+	}
+
+	c.upvalues = append(c.upvalues, upvalue{index: index, isLocal: isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *compiler) emit(node ast.Node, op Op, operands ...byte) {
+	c.fn.Chunk.write(node.Loc.Start, byte(op))
+	c.fn.Chunk.write(node.Loc.Start, operands...)
+}
+
+func (c *compiler) emitConstant(node ast.Node, v interface{}) error {
+	idx, err := c.makeConstant(node, v)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node, OpConstant, uint16Operand(idx)...)
+	return nil
+}
+
+// emitNamed emits the instruction with the name constant as the operand.
+func (c *compiler) emitNamed(node ast.Node, op Op, name string) error {
+	idx, err := c.makeConstant(node, name)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node, op, uint16Operand(idx)...)
+	return nil
+}
+
+func (c *compiler) makeConstant(node ast.Node, v interface{}) (int, error) {
+	_, isFunc := v.(*Function)
+	if idx, ok := c.constants[v]; ok && !isFunc {
+		return idx, nil
+	}
+
+	idx := len(c.fn.Chunk.Constants)
+	if idx >= maxConstants {
+		return 0, errorf(node, "too many constants in function")
+	}
+
+	c.fn.Chunk.Constants = append(c.fn.Chunk.Constants, v)
+	if !isFunc {
+		c.constants[v] = idx
+	}
+	return idx, nil
+}
+
+// emitJump emits the jump instruction and returns the offset of its operand
+// to be patched once the target is known.
+func (c *compiler) emitJump(node ast.Node, op Op) int {
+	c.emit(node, op, 0xff, 0xff)
+	return len(c.fn.Chunk.Code) - 2
+}
+
+func (c *compiler) patchJump(node ast.Node, offset int) error {
+	jump := len(c.fn.Chunk.Code) - offset - 2
+	if jump > maxJump {
+		return errorf(node, "too much code to jump over")
+	}
+
+	c.fn.Chunk.Code[offset] = byte(jump >> 8)
+	c.fn.Chunk.Code[offset+1] = byte(jump)
+	return nil
+}
+
+func (c *compiler) patchJumps(node ast.Node, offsets []int) error {
+	for _, offset := range offsets {
+		if err := c.patchJump(node, offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) emitLoop(node ast.Node, loopStart int) error {
+	jump := len(c.fn.Chunk.Code) + 3 - loopStart
+	if jump > maxJump {
+		return errorf(node, "loop body is too large")
+	}
+
+	c.emit(node, OpLoop, uint16Operand(jump)...)
+	return nil
+}
+
+func uint16Operand(v int) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
+
+func identName(n ast.Node) string {
+	return n.Fields.(*ast.Identifier).Name
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func errorf(node ast.Node, format string, args ...interface{}) error {
+	return &CompileError{
+		Loc: node.Loc,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,109 @@
+package bytecode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+func TestCompile_Disassemble(t *testing.T) {
+	in := `
+let x = 1;
+def add(a) {
+	return a + x;
+}
+while (x < 10) x = add(2);
+`
+	want := `== <script> ==
+0000    2:9 OpConstant          0 1
+0003    2:5 OpDefineGlobal      1 "x"
+0006    3:1 OpClosure           2 <function add>
+0009    3:1 OpDefineGlobal      3 "add"
+0012    6:8 OpGetGlobal         1 "x"
+0015   6:12 OpConstant          4 10
+0018    6:8 OpLess
+0019    6:1 OpJumpIfFalse      16 -> 0038
+0022    6:1 OpPop
+0023   6:20 OpGetGlobal         3 "add"
+0026   6:24 OpConstant          5 2
+0029   6:20 OpCall              1
+0031   6:16 OpSetGlobal         1 "x"
+0034   6:16 OpPop
+0035    6:1 OpLoop             26 -> 0012
+0038    6:1 OpPop
+0039    2:1 OpNull
+0040    2:1 OpReturn
+
+== add ==
+0000    4:9 OpGetLocal          1
+0002   4:13 OpGetGlobal         0 "x"
+0005    4:9 OpAdd
+0006    4:2 OpReturn
+0007   3:12 OpNull
+0008   3:12 OpReturn
+`
+
+	fn, err := Compile(mustParse(t, in))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, Disassemble(&out, fn))
+	assert.Equal(t, want, out.String())
+}
+
+func TestCompile_Error(t *testing.T) {
+	type test struct {
+		in      string
+		wantErr string
+	}
+	tests := []test{
+		{
+			in:      `return 1;`,
+			wantErr: `1:1: return outside of function`,
+		}, {
+			in:      `def f() { return this; }`,
+			wantErr: `1:18: 'this' outside of method`,
+		}, {
+			in:      `def f() { super(); }`,
+			wantErr: `1:11: 'super' outside of method`,
+		}, {
+			in:      `class A { def m() { def f() { super(); } } }`,
+			wantErr: `1:31: 'super' outside of method`,
+		}, {
+			in:      `class A extends B { def m() { return {n() { return super(); }}; } }`,
+			wantErr: `1:52: 'super' outside of method`,
+		}, {
+			in:      `class A extends B { def m() { return () => super(); } }`,
+			wantErr: `1:44: 'super' outside of method`,
+		}, {
+			in:      `class A { def m() { return super(); } }`,
+			wantErr: `1:28: class A has no parent class`,
+		}, {
+			in:      `class A { let x = 1; }`,
+			wantErr: `1:11: class body may only contain methods, got VarStmtType`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			_, err := Compile(mustParse(t, tc.in))
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func mustParse(t *testing.T, in string) ast.Node {
+	tok := tokenizer.NewScanner(in)
+	node, err := parser.NewParser(tok, ast.Builder{}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
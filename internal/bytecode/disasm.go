@@ -0,0 +1,103 @@
+package bytecode
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Disassemble writes the human readable listing of the function and all the
+// functions nested in it.
+func Disassemble(w io.Writer, fn *Function) error {
+	name := fn.Name
+	if name == "" {
+		name = fn.String()
+	}
+	if _, err := fmt.Fprintf(w, "== %s ==\n", name); err != nil {
+		return err
+	}
+
+	chunk := &fn.Chunk
+	for offset := 0; offset < len(chunk.Code); {
+		var err error
+		if offset, err = disassembleInstruction(w, chunk, offset); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range chunk.Constants {
+		if nested, ok := c.(*Function); ok {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+			if err := Disassemble(w, nested); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// disassembleInstruction writes the instruction at the offset and returns
+// the offset of the next one.
+func disassembleInstruction(w io.Writer, chunk *Chunk, offset int) (int, error) {
+	op := Op(chunk.Code[offset])
+	pos := chunk.Pos[offset]
+	prefix := fmt.Sprintf("%04d %6s %-16s", offset, fmt.Sprintf("%d:%d", pos.Line, pos.Column), op)
+
+	var line string
+	next := offset + 1
+
+	switch op {
+	case OpConstant, OpGetGlobal, OpSetGlobal, OpDefineGlobal,
+		OpGetProp, OpSetProp, OpSuper, OpClass, OpMethod, OpDefineProp, OpDefineMethod:
+		idx := chunk.ReadUint16(offset + 1)
+		line = fmt.Sprintf("%s %4d %s", prefix, idx, formatConstant(chunk.Constants[idx]))
+		next += 2
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall, OpNew, OpTuck:
+		line = fmt.Sprintf("%s %4d", prefix, chunk.Code[offset+1])
+		next++
+	case OpArray:
+		line = fmt.Sprintf("%s %4d", prefix, chunk.ReadUint16(offset+1))
+		next += 2
+	case OpJump, OpJumpIfFalse, OpJumpIfTrue, OpTry, OpTryFinally:
+		line = fmt.Sprintf("%s %4d -> %04d", prefix, chunk.ReadUint16(offset+1), offset+3+chunk.ReadUint16(offset+1))
+		next += 2
+	case OpLoop:
+		line = fmt.Sprintf("%s %4d -> %04d", prefix, chunk.ReadUint16(offset+1), offset+3-chunk.ReadUint16(offset+1))
+		next += 2
+	case OpClosure:
+		idx := chunk.ReadUint16(offset + 1)
+		fn := chunk.Constants[idx].(*Function)
+		line = fmt.Sprintf("%s %4d %s", prefix, idx, formatConstant(fn))
+		next += 2
+		for i := 0; i < fn.UpvalueCount; i++ {
+			kind := "upvalue"
+			if chunk.Code[next] == 1 {
+				kind = "local"
+			}
+			line += fmt.Sprintf("\n%04d %6s %-16s %s %d", next, "|", "", kind, chunk.Code[next+1])
+			next += 2
+		}
+	default:
+		line = prefix
+	}
+
+	_, err := fmt.Fprintln(w, strings.TrimRight(line, " "))
+	return next, err
+}
+
+func formatConstant(c interface{}) string {
+	switch c := c.(type) {
+	case float64:
+		return strconv.FormatFloat(c, 'g', -1, 64)
+	case string:
+		return strconv.Quote(c)
+	case *Function:
+		return c.String()
+	default:
+		return fmt.Sprintf("%v", c)
+	}
+}
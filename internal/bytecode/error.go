@@ -0,0 +1,17 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+// CompileError is an error in the program found while compiling it.
+type CompileError struct {
+	Loc ast.Loc
+	Msg string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Loc.Start.Line, e.Loc.Start.Column, e.Msg)
+}
@@ -0,0 +1,160 @@
+package bytecode
+
+// Op is a bytecode instruction. Operands follow the opcode in the code,
+// 16 bit operands are big-endian.
+type Op byte
+
+const (
+	OpConstant Op = iota // idx16: push constant
+	OpNull               // push null
+	OpTrue               // push true
+	OpFalse              // push false
+	OpArray              // len16: replace the elements on the top of the stack with the array of them
+	OpObject             // push a new empty object
+	OpPop                // pop the top of the stack
+	OpDup                // duplicate the top of the stack
+	OpDup2               // duplicate the two values on the top of the stack
+	OpTuck               // n8: copy the top of the stack below the n values under it
+
+	OpGetLocal     // slot8: push local variable
+	OpSetLocal     // slot8: set local variable to the top of the stack
+	OpGetUpvalue   // idx8: push captured variable
+	OpSetUpvalue   // idx8: set captured variable to the top of the stack
+	OpCloseUpvalue // move the local on the top of the stack to the heap and pop it
+	OpGetGlobal    // name16: push global variable
+	OpSetGlobal    // name16: set global variable to the top of the stack
+	OpDefineGlobal // name16: pop the value into a new global variable
+
+	OpGetProp  // name16: replace the object with its property
+	OpSetProp  // name16: pop value and object, set property, push value
+	OpGetIndex // replace the object and the key with the property
+	OpSetIndex // pop value, key and object, set property, push value
+	OpSuper    // name16: push the method of the parent class bound to 'this'
+
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpLess
+	OpGreaterEqual
+	OpLessEqual
+	OpInstanceof
+	OpIn
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpShl
+	OpShr
+	OpUShr
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpNot
+	OpNeg
+	OpBitNot
+	OpInc    // add one to the number on the top of the stack
+	OpDec    // subtract one from the number on the top of the stack
+	OpTypeof // replace the value with the name of its type
+
+	OpJump        // off16: jump forward
+	OpJumpIfFalse // off16: jump forward if the top of the stack is falsy, doesn't pop
+	OpJumpIfTrue  // off16: jump forward if the top of the stack is truthy, doesn't pop
+	OpLoop        // off16: jump backward
+	OpTry         // off16: install the handler at the offset forward
+	OpTryFinally  // off16: install the handler at the offset forward, it gets the thrown value wrapped with its position
+	OpEndTry      // uninstall the innermost handler
+	OpThrow       // pop the value and unwind the stack to the innermost handler, the wrapped value is thrown further
+
+	OpCall    // argc8: call the callee below the arguments
+	OpNew     // argc8: instantiate the class below the arguments
+	OpClosure // idx16 (isLocal8 index8)*: push closure of the function constant
+	OpReturn  // return the top of the stack from the function
+
+	OpClass   // name16: push a new class
+	OpInherit // pop the parent class and set it for the class below
+	OpMethod  // name16: pop the closure and add it to the class below as a method
+
+	OpDefineProp   // name16: pop the value and add it to the object below as a property
+	OpDefineIndex  // pop the value and the key and add the value to the object below as a property
+	OpDefineMethod // name16: pop the closure and add it to the object below as a method
+)
+
+var opNames = [...]string{
+	OpConstant:     "OpConstant",
+	OpNull:         "OpNull",
+	OpTrue:         "OpTrue",
+	OpFalse:        "OpFalse",
+	OpArray:        "OpArray",
+	OpObject:       "OpObject",
+	OpPop:          "OpPop",
+	OpDup:          "OpDup",
+	OpDup2:         "OpDup2",
+	OpTuck:         "OpTuck",
+	OpGetLocal:     "OpGetLocal",
+	OpSetLocal:     "OpSetLocal",
+	OpGetUpvalue:   "OpGetUpvalue",
+	OpSetUpvalue:   "OpSetUpvalue",
+	OpCloseUpvalue: "OpCloseUpvalue",
+	OpGetGlobal:    "OpGetGlobal",
+	OpSetGlobal:    "OpSetGlobal",
+	OpDefineGlobal: "OpDefineGlobal",
+	OpGetProp:      "OpGetProp",
+	OpSetProp:      "OpSetProp",
+	OpGetIndex:     "OpGetIndex",
+	OpSetIndex:     "OpSetIndex",
+	OpSuper:        "OpSuper",
+	OpEqual:        "OpEqual",
+	OpNotEqual:     "OpNotEqual",
+	OpGreater:      "OpGreater",
+	OpLess:         "OpLess",
+	OpGreaterEqual: "OpGreaterEqual",
+	OpLessEqual:    "OpLessEqual",
+	OpInstanceof:   "OpInstanceof",
+	OpIn:           "OpIn",
+	OpAdd:          "OpAdd",
+	OpSub:          "OpSub",
+	OpMul:          "OpMul",
+	OpDiv:          "OpDiv",
+	OpMod:          "OpMod",
+	OpPow:          "OpPow",
+	OpShl:          "OpShl",
+	OpShr:          "OpShr",
+	OpUShr:         "OpUShr",
+	OpBitAnd:       "OpBitAnd",
+	OpBitOr:        "OpBitOr",
+	OpBitXor:       "OpBitXor",
+	OpNot:          "OpNot",
+	OpNeg:          "OpNeg",
+	OpBitNot:       "OpBitNot",
+	OpInc:          "OpInc",
+	OpDec:          "OpDec",
+	OpTypeof:       "OpTypeof",
+	OpJump:         "OpJump",
+	OpJumpIfFalse:  "OpJumpIfFalse",
+	OpJumpIfTrue:   "OpJumpIfTrue",
+	OpLoop:         "OpLoop",
+	OpTry:          "OpTry",
+	OpTryFinally:   "OpTryFinally",
+	OpEndTry:       "OpEndTry",
+	OpThrow:        "OpThrow",
+	OpCall:         "OpCall",
+	OpNew:          "OpNew",
+	OpClosure:      "OpClosure",
+	OpReturn:       "OpReturn",
+	OpClass:        "OpClass",
+	OpInherit:      "OpInherit",
+	OpMethod:       "OpMethod",
+	OpDefineProp:   "OpDefineProp",
+	OpDefineIndex:  "OpDefineIndex",
+	OpDefineMethod: "OpDefineMethod",
+}
+
+func (o Op) String() string {
+	if int(o) < len(opNames) && opNames[o] != "" {
+		return opNames[o]
+	}
+
+	return "OpUnknown"
+}
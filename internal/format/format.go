@@ -0,0 +1,31 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/printer"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+// Source formats the program in the canonical style keeping its comments.
+// If the program has syntax errors they are returned as parser.ErrorList.
+func Source(src string) ([]byte, error) {
+	var b ast.Builder
+
+	p := parser.NewParser(tokenizer.NewScanner(src), b)
+	p.KeepComments()
+
+	program, errs := p.ParseRecover()
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, program); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+)
+
+func TestSource(t *testing.T) {
+	in := `// Package comment
+
+
+class   Point extends Base{ // the point
+  /* coordinates */ def constructor(x,y){super( );this.x=x;this.y = y;}
+  def len( ) { return this.x*this.x+this.y*this.y ; } // squared
+}
+let p=new Point(1,2) ;
+/*
+ * Loop
+ */
+for(let i=0;i<10;i+=1) if (p.len() > i) print(i) ; else { print("no"); }
+// trailing
+`
+	want := `// Package comment
+
+class Point extends Base { // the point
+	/* coordinates */
+	def constructor(x, y) {
+		super();
+		this.x = x;
+		this.y = y;
+	}
+
+	def len() {
+		return this.x * this.x + this.y * this.y;
+	} // squared
+}
+let p = new Point(1, 2);
+/*
+ * Loop
+ */
+for (let i = 0; i < 10; i += 1)
+	if (p.len() > i)
+		print(i);
+	else {
+		print("no");
+	}
+// trailing
+`
+
+	got, err := Source(in)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+
+	again, err := Source(string(got))
+	require.NoError(t, err)
+	assert.Equal(t, want, string(again))
+}
+
+func TestSource_Error(t *testing.T) {
+	_, err := Source("let x = ;\nlet = 1;")
+
+	var errs parser.ErrorList
+	require.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, err, `1:9: unexpected token, ";(;)", expected: "PrimaryExpr" (and 1 more errors)`)
+}
@@ -0,0 +1,62 @@
+package interp
+
+import (
+	"fmt"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/value"
+)
+
+// RuntimeError is an error raised while evaluating the program, it points to
+// the node which has failed.
+type RuntimeError struct {
+	Loc ast.Loc
+	Msg string
+}
+
+func (e *RuntimeError) Error() string {
+	if !e.Loc.IsValid() {
+		return e.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Loc.Start.Line, e.Loc.Start.Column, e.Msg)
+}
+
+// returnSignal unwinds the evaluation up to the enclosing function call.
+type returnSignal struct {
+	value Value
+}
+
+func (r *returnSignal) Error() string {
+	return "return outside of function"
+}
+
+// breakSignal unwinds the evaluation up to the loop or the statement with
+// the label, the label is empty for the innermost loop.
+type breakSignal struct {
+	label string
+}
+
+func (b *breakSignal) Error() string {
+	return "break outside of loop or switch"
+}
+
+// throwSignal unwinds the evaluation up to the enclosing try statement, the
+// node is the throw statement.
+type throwSignal struct {
+	node  ast.Node
+	value Value
+}
+
+func (t *throwSignal) Error() string {
+	return fmt.Sprintf("uncaught exception: %s", value.ToString(t.value))
+}
+
+// continueSignal unwinds the evaluation up to the next iteration of the
+// loop with the label, the label is empty for the innermost loop.
+type continueSignal struct {
+	label string
+}
+
+func (c *continueSignal) Error() string {
+	return "continue outside of loop"
+}
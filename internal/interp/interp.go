@@ -0,0 +1,882 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/value"
+)
+
+// maxCallDepth limits the recursion of the evaluated program so that it
+// fails with a runtime error instead of exhausting the Go stack.
+const maxCallDepth = 10000
+
+// Interpreter evaluates the AST produced by the parser directly.
+type Interpreter struct {
+	out     io.Writer
+	globals *env
+	depth   int
+}
+
+func NewInterpreter(out io.Writer) *Interpreter {
+	i := &Interpreter{
+		out:     out,
+		globals: newEnv(nil),
+	}
+	i.globals.define("print", &value.Builtin{Name: "print", Fn: i.print})
+
+	return i
+}
+
+// Run evaluates the program. Globals defined by the program are kept between
+// the runs.
+func (i *Interpreter) Run(program ast.Node) error {
+	prog, ok := program.Fields.(*ast.Program)
+	if !ok {
+		return errorf(program, "expected program, got %s", program.Type)
+	}
+
+	for _, stmt := range prog.Body {
+		if err := i.exec(stmt, i.globals); err != nil {
+			switch err.(type) {
+			case *returnSignal, *breakSignal, *continueSignal:
+				return errorf(stmt, "%s", err)
+			case *throwSignal:
+				return errorf(err.(*throwSignal).node, "%s", err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Interpreter) print(args []Value) (Value, error) {
+	strs := make([]string, len(args))
+	for n, arg := range args {
+		strs[n] = value.ToString(arg)
+	}
+	_, err := fmt.Fprintln(i.out, strings.Join(strs, " "))
+	return nil, err
+}
+
+func (i *Interpreter) exec(node ast.Node, scope *env) error {
+	switch n := node.Fields.(type) {
+	case *ast.ExprStmt:
+		_, err := i.eval(n.Expr, scope)
+		return err
+	case *ast.EmptyStmt:
+		return nil
+	case *ast.BlockStmt:
+		return i.execList(n.Body, newEnv(scope))
+	case *ast.VarStmt:
+		return i.execVarStmt(n, scope)
+	case *ast.IfStmt:
+		return i.execIfStmt(n, scope)
+	case *ast.WhileStmt:
+		return i.execWhileStmt(n, scope, nil)
+	case *ast.DoWhileStmt:
+		return i.execDoWhileStmt(n, scope, nil)
+	case *ast.ForStmt:
+		return i.execForStmt(n, scope, nil)
+	case *ast.BreakStmt:
+		return &breakSignal{label: labelName(n.Label)}
+	case *ast.ContinueStmt:
+		return &continueSignal{label: labelName(n.Label)}
+	case *ast.LabeledStmt:
+		return i.execLabeledStmt(node, scope)
+	case *ast.TryStmt:
+		return i.execTryStmt(n, scope)
+	case *ast.SwitchStmt:
+		return i.execSwitchStmt(n, scope)
+	case *ast.ThrowStmt:
+		v, err := i.eval(n.Arg, scope)
+		if err != nil {
+			return err
+		}
+		return &throwSignal{node: node, value: v}
+	case *ast.FuncDecl:
+		fn := i.newFunction(identName(n.Name), n.Params, n.Body, scope, nil)
+		scope.define(fn.Name, fn)
+		return nil
+	case *ast.ReturnStmt:
+		var v Value
+		if n.Arg != nil {
+			var err error
+			if v, err = i.eval(n.Arg, scope); err != nil {
+				return err
+			}
+		}
+		return &returnSignal{value: v}
+	case *ast.ClassDecl:
+		return i.execClassDecl(n, scope)
+	case *ast.BadStmt:
+		return errorf(node, "cannot run a statement with syntax errors")
+	default:
+		return errorf(node, "unexpected statement %s", node.Type)
+	}
+}
+
+func (i *Interpreter) execList(body []ast.Node, scope *env) error {
+	for _, stmt := range body {
+		if err := i.exec(stmt, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) execVarStmt(n *ast.VarStmt, scope *env) error {
+	for _, d := range n.Decls {
+		decl := d.Fields.(*ast.VarDecl)
+
+		var v Value
+		if decl.Init != nil {
+			var err error
+			if v, err = i.eval(decl.Init, scope); err != nil {
+				return err
+			}
+		}
+		scope.define(identName(decl.ID), v)
+	}
+	return nil
+}
+
+func (i *Interpreter) execIfStmt(n *ast.IfStmt, scope *env) error {
+	cond, err := i.eval(n.Cond, scope)
+	if err != nil {
+		return err
+	}
+
+	if value.IsTruthy(cond) {
+		return i.exec(n.Cons, scope)
+	}
+	if n.Alt != nil {
+		return i.exec(n.Alt, scope)
+	}
+	return nil
+}
+
+// execLabeledStmt executes the statement with all of its labels, the loop
+// gets the labels for the continue statements to refer to.
+func (i *Interpreter) execLabeledStmt(node ast.Node, scope *env) error {
+	var labels []string
+	for {
+		n, ok := node.Fields.(*ast.LabeledStmt)
+		if !ok {
+			break
+		}
+		labels = append(labels, identName(n.Label))
+		node = n.Body
+	}
+
+	var err error
+	switch n := node.Fields.(type) {
+	case *ast.WhileStmt:
+		err = i.execWhileStmt(n, scope, labels)
+	case *ast.DoWhileStmt:
+		err = i.execDoWhileStmt(n, scope, labels)
+	case *ast.ForStmt:
+		err = i.execForStmt(n, scope, labels)
+	default:
+		err = i.exec(node, scope)
+	}
+
+	if sig, ok := err.(*breakSignal); ok && hasLabel(labels, sig.label) {
+		return nil
+	}
+	return err
+}
+
+// execTryStmt executes the handler when the block throws and then the
+// finalizer. The signal leaving the finalizer replaces the one leaving the
+// block or the handler. Runtime errors are not caught.
+func (i *Interpreter) execTryStmt(n *ast.TryStmt, scope *env) error {
+	err := i.exec(n.Block, scope)
+	if sig, ok := err.(*throwSignal); ok && n.Handler != nil {
+		handler := n.Handler.Fields.(*ast.CatchClause)
+		catchScope := newEnv(scope)
+		catchScope.define(identName(handler.Param), sig.value)
+		err = i.execList(handler.Body.Fields.(*ast.BlockStmt).Body, catchScope)
+	}
+
+	if n.Finalizer != nil {
+		if ferr := i.exec(n.Finalizer, scope); ferr != nil {
+			return ferr
+		}
+	}
+	return err
+}
+
+// execSwitchStmt executes the cases starting from the first one whose test
+// equals the discriminant, the default case is the last one tried. The
+// cases fall through up to the break statement.
+func (i *Interpreter) execSwitchStmt(n *ast.SwitchStmt, scope *env) error {
+	disc, err := i.eval(n.Disc, scope)
+	if err != nil {
+		return err
+	}
+
+	match, def := -1, -1
+	for idx, c := range n.Cases {
+		test := c.Fields.(*ast.SwitchCase).Test
+		if test == nil {
+			def = idx
+			continue
+		}
+		v, err := i.eval(test, scope)
+		if err != nil {
+			return err
+		}
+		if value.IsEqual(disc, v) {
+			match = idx
+			break
+		}
+	}
+	if match < 0 {
+		if match = def; match < 0 {
+			return nil
+		}
+	}
+
+	for _, c := range n.Cases[match:] {
+		err := i.execList(c.Fields.(*ast.SwitchCase).Body, newEnv(scope))
+		if sig, ok := err.(*breakSignal); ok && sig.label == "" {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execLoopBody executes the body of the loop with the labels, it reports
+// whether the loop is done because of the break statement.
+func (i *Interpreter) execLoopBody(body ast.Node, scope *env, labels []string) (bool, error) {
+	err := i.exec(body, scope)
+	switch sig := err.(type) {
+	case *breakSignal:
+		if sig.label == "" || hasLabel(labels, sig.label) {
+			return true, nil
+		}
+	case *continueSignal:
+		if sig.label == "" || hasLabel(labels, sig.label) {
+			return false, nil
+		}
+	}
+	return false, err
+}
+
+func (i *Interpreter) execWhileStmt(n *ast.WhileStmt, scope *env, labels []string) error {
+	for {
+		cond, err := i.eval(n.Cond, scope)
+		if err != nil {
+			return err
+		}
+		if !value.IsTruthy(cond) {
+			return nil
+		}
+		if done, err := i.execLoopBody(n.Body, scope, labels); done || err != nil {
+			return err
+		}
+	}
+}
+
+func (i *Interpreter) execDoWhileStmt(n *ast.DoWhileStmt, scope *env, labels []string) error {
+	for {
+		if done, err := i.execLoopBody(n.Body, scope, labels); done || err != nil {
+			return err
+		}
+		cond, err := i.eval(n.Cond, scope)
+		if err != nil {
+			return err
+		}
+		if !value.IsTruthy(cond) {
+			return nil
+		}
+	}
+}
+
+func (i *Interpreter) execForStmt(n *ast.ForStmt, scope *env, labels []string) error {
+	scope = newEnv(scope)
+
+	if n.Init != nil {
+		var err error
+		if _, ok := n.Init.Fields.(*ast.VarStmt); ok {
+			err = i.exec(n.Init, scope)
+		} else {
+			_, err = i.eval(n.Init, scope)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		if n.Cond != nil {
+			cond, err := i.eval(n.Cond, scope)
+			if err != nil {
+				return err
+			}
+			if !value.IsTruthy(cond) {
+				return nil
+			}
+		}
+		if done, err := i.execLoopBody(n.Body, scope, labels); done || err != nil {
+			return err
+		}
+		if n.Step != nil {
+			if _, err := i.eval(n.Step, scope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (i *Interpreter) execClassDecl(n *ast.ClassDecl, scope *env) error {
+	class := value.NewClass(identName(n.ID))
+
+	if n.Super != nil {
+		super, err := i.eval(n.Super, scope)
+		if err != nil {
+			return err
+		}
+		superClass, ok := super.(*value.Class)
+		if !ok {
+			return errorf(n.Super, "class %s can't extend %s", class.Name, value.TypeName(super))
+		}
+		class.Super = superClass
+	}
+
+	for _, member := range n.Body.Fields.(*ast.BlockStmt).Body {
+		decl, ok := member.Fields.(*ast.FuncDecl)
+		if !ok {
+			return errorf(member, "class body may only contain methods, got %s", member.Type)
+		}
+		m := i.newFunction(identName(decl.Name), decl.Params, decl.Body, scope, class)
+		m.method = true
+		class.Methods[m.Name] = m
+	}
+
+	scope.define(class.Name, class)
+	return nil
+}
+
+// newFunction creates the function closed over the scope, the body is either
+// a block statement or the expression of the arrow function.
+func (i *Interpreter) newFunction(name string, paramNodes []ast.Node, body ast.Node, scope *env, class *value.Class) *Function {
+	params := make([]string, len(paramNodes))
+	for k, param := range paramNodes {
+		params[k] = identName(param)
+	}
+
+	return &Function{
+		Name:    name,
+		params:  params,
+		body:    body,
+		closure: scope,
+		class:   class,
+	}
+}
+
+func (i *Interpreter) eval(node ast.Node, scope *env) (Value, error) {
+	switch n := node.Fields.(type) {
+	case *ast.NumericLit:
+		return n.Float64(), nil
+	case *ast.StringLit:
+		return n.Value, nil
+	case *ast.BoolLit:
+		return n.Value, nil
+	case *ast.NullLit:
+		return nil, nil
+	case *ast.ArrayLit:
+		elements := make([]Value, len(n.Elements))
+		for k, el := range n.Elements {
+			if el == nil {
+				continue
+			}
+			v, err := i.eval(el, scope)
+			if err != nil {
+				return nil, err
+			}
+			elements[k] = v
+		}
+		return &value.Array{Elements: elements}, nil
+	case *ast.ObjectLit:
+		return i.evalObjectLit(n, scope)
+	case *ast.FuncExpr:
+		if n.Name == nil {
+			return i.newFunction("", n.Params, n.Body, scope, nil), nil
+		}
+		// the name of the function expression is visible only inside of it
+		scope = newEnv(scope)
+		fn := i.newFunction(identName(n.Name), n.Params, n.Body, scope, nil)
+		scope.define(fn.Name, fn)
+		return fn, nil
+	case *ast.ArrowFunc:
+		return i.newFunction("", n.Params, n.Body, scope, nil), nil
+	case *ast.Identifier:
+		v, ok := scope.lookup(n.Name)
+		if !ok {
+			return nil, errorf(node, "%s is not defined", n.Name)
+		}
+		return v, nil
+	case *ast.ThisExpr:
+		v, ok := scope.lookup("this")
+		if !ok {
+			return nil, errorf(node, "'this' outside of method")
+		}
+		return v, nil
+	case *ast.SeqExpr:
+		var v Value
+		for _, expr := range n.Body {
+			var err error
+			if v, err = i.eval(expr, scope); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case *ast.UnaryExpr:
+		return i.evalUnaryExpr(node, n, scope)
+	case *ast.UpdateExpr:
+		return i.evalUpdateExpr(node, n, scope)
+	case *ast.BinaryExpr:
+		left, err := i.eval(n.Left, scope)
+		if err != nil {
+			return nil, err
+		}
+		right, err := i.eval(n.Right, scope)
+		if err != nil {
+			return nil, err
+		}
+		v, err := value.BinaryOp(n.Op, left, right)
+		return v, errorAt(node, err)
+	case *ast.LogicalExpr:
+		return i.evalLogicalExpr(n, scope)
+	case *ast.ConditionalExpr:
+		cond, err := i.eval(n.Cond, scope)
+		if err != nil {
+			return nil, err
+		}
+		if value.IsTruthy(cond) {
+			return i.eval(n.Cons, scope)
+		}
+		return i.eval(n.Alt, scope)
+	case *ast.AssignExpr:
+		return i.evalAssignExpr(node, n, scope)
+	case *ast.MemberExpr:
+		obj, err := i.eval(n.Obj, scope)
+		if err != nil {
+			return nil, err
+		}
+		key, err := i.propKey(n, scope)
+		if err != nil {
+			return nil, err
+		}
+		v, err := value.GetProp(obj, key)
+		return v, errorAt(node, err)
+	case *ast.CallExpr:
+		return i.evalCallExpr(node, n, scope)
+	case *ast.NewExpr:
+		return i.evalNewExpr(node, n, scope)
+	default:
+		return nil, errorf(node, "unexpected expression %s", node.Type)
+	}
+}
+
+func (i *Interpreter) evalObjectLit(n *ast.ObjectLit, scope *env) (Value, error) {
+	obj := value.NewObject()
+	for _, node := range n.Props {
+		prop := node.Fields.(*ast.Property)
+
+		var key string
+		switch k := prop.Key.Fields.(type) {
+		case *ast.Identifier:
+			key = k.Name
+		case *ast.StringLit:
+			key = k.Value
+		case *ast.NumericLit:
+			key = value.FormatNumber(k.Float64())
+		}
+		if prop.Computed {
+			v, err := i.eval(prop.Key, scope)
+			if err != nil {
+				return nil, err
+			}
+			key = value.ToString(v)
+		}
+
+		if prop.Kind == ast.MethodProperty {
+			decl := prop.Value.Fields.(*ast.FuncDecl)
+			m := i.newFunction(identName(decl.Name), decl.Params, decl.Body, scope, nil)
+			m.method = true
+			obj.Set(key, m)
+			continue
+		}
+
+		v, err := i.eval(prop.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(key, v)
+	}
+
+	return obj, nil
+}
+
+func (i *Interpreter) evalUnaryExpr(node ast.Node, n *ast.UnaryExpr, scope *env) (Value, error) {
+	arg, err := i.eval(n.Arg, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case ast.NotUnaryOp:
+		return !value.IsTruthy(arg), nil
+	case ast.NegUnaryOp:
+		num, ok := arg.(float64)
+		if !ok {
+			return nil, errorf(node, "bad operand type for unary -: %s", value.TypeName(arg))
+		}
+		return -num, nil
+	case ast.BitNotUnaryOp:
+		num, ok := arg.(float64)
+		if !ok {
+			return nil, errorf(node, "bad operand type for unary ~: %s", value.TypeName(arg))
+		}
+		return float64(^value.ToInt32(num)), nil
+	case ast.TypeofUnaryOp:
+		return value.TypeName(arg), nil
+	default:
+		return nil, errorf(node, "unknown unary operator %s", n.Op)
+	}
+}
+
+func (i *Interpreter) evalUpdateExpr(node ast.Node, n *ast.UpdateExpr, scope *env) (Value, error) {
+	// update returns the new value of the target and the value of the
+	// expression, which is the old one for the postfix form.
+	update := func(old Value) (Value, Value, error) {
+		num, ok := old.(float64)
+		if !ok {
+			return nil, nil, errorf(node, "bad operand type for %s: %s", n.Op, value.TypeName(old))
+		}
+		v := num + 1
+		if n.Op == ast.DecUpdateOp {
+			v = num - 1
+		}
+		if n.Prefix {
+			return v, v, nil
+		}
+		return v, num, nil
+	}
+
+	switch target := n.Arg.Fields.(type) {
+	case *ast.Identifier:
+		old, ok := scope.lookup(target.Name)
+		if !ok {
+			return nil, errorf(n.Arg, "%s is not defined", target.Name)
+		}
+		v, result, err := update(old)
+		if err != nil {
+			return nil, err
+		}
+		scope.assign(target.Name, v)
+		return result, nil
+	case *ast.MemberExpr:
+		obj, err := i.eval(target.Obj, scope)
+		if err != nil {
+			return nil, err
+		}
+		key, err := i.propKey(target, scope)
+		if err != nil {
+			return nil, err
+		}
+		old, err := value.GetProp(obj, key)
+		if err != nil {
+			return nil, errorAt(n.Arg, err)
+		}
+		v, result, err := update(old)
+		if err != nil {
+			return nil, err
+		}
+		if err := value.SetProp(obj, key, v); err != nil {
+			return nil, errorAt(n.Arg, err)
+		}
+		return result, nil
+	default:
+		return nil, errorf(n.Arg, "invalid assignment target %s", n.Arg.Type)
+	}
+}
+
+func (i *Interpreter) evalLogicalExpr(n *ast.LogicalExpr, scope *env) (Value, error) {
+	left, err := i.eval(n.Left, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if (n.Op == ast.AndLogicalOp) != value.IsTruthy(left) {
+		return left, nil
+	}
+	return i.eval(n.Right, scope)
+}
+
+// compoundAssignOps maps compound assignment operators to the binary
+// operators they apply.
+var compoundAssignOps = map[ast.AssignOp]ast.BinaryOp{
+	ast.AddAssignOp:    ast.AddBinaryOp,
+	ast.SubAssignOp:    ast.SubBinaryOp,
+	ast.MulAssignOp:    ast.MulBinaryOp,
+	ast.DivAssignOp:    ast.DivBinaryOp,
+	ast.ModAssignOp:    ast.ModBinaryOp,
+	ast.ExpAssignOp:    ast.ExpBinaryOp,
+	ast.ShlAssignOp:    ast.ShlBinaryOp,
+	ast.ShrAssignOp:    ast.ShrBinaryOp,
+	ast.UShrAssignOp:   ast.UShrBinaryOp,
+	ast.BitAndAssignOp: ast.BitAndBinaryOp,
+	ast.BitOrAssignOp:  ast.BitOrBinaryOp,
+	ast.BitXorAssignOp: ast.BitXorBinaryOp,
+}
+
+func (i *Interpreter) evalAssignExpr(node ast.Node, n *ast.AssignExpr, scope *env) (Value, error) {
+	compound := n.Op != ast.SimpleAssignOp
+
+	// right evaluates the right-hand side and applies the compound operator
+	// to the current value of the target.
+	right := func(left Value) (Value, error) {
+		v, err := i.eval(n.Right, scope)
+		if err != nil {
+			return nil, err
+		}
+		if compound {
+			v, err = value.BinaryOp(compoundAssignOps[n.Op], left, v)
+			return v, errorAt(node, err)
+		}
+		return v, nil
+	}
+
+	switch target := n.Left.Fields.(type) {
+	case *ast.Identifier:
+		var left Value
+		if compound {
+			var ok bool
+			if left, ok = scope.lookup(target.Name); !ok {
+				return nil, errorf(n.Left, "%s is not defined", target.Name)
+			}
+		}
+		v, err := right(left)
+		if err != nil {
+			return nil, err
+		}
+		if !scope.assign(target.Name, v) {
+			return nil, errorf(n.Left, "%s is not defined", target.Name)
+		}
+		return v, nil
+	case *ast.MemberExpr:
+		obj, err := i.eval(target.Obj, scope)
+		if err != nil {
+			return nil, err
+		}
+		key, err := i.propKey(target, scope)
+		if err != nil {
+			return nil, err
+		}
+		var left Value
+		if compound {
+			if left, err = value.GetProp(obj, key); err != nil {
+				return nil, errorAt(n.Left, err)
+			}
+		}
+		v, err := right(left)
+		if err != nil {
+			return nil, err
+		}
+		if err := value.SetProp(obj, key, v); err != nil {
+			return nil, errorAt(n.Left, err)
+		}
+		return v, nil
+	default:
+		return nil, errorf(n.Left, "invalid assignment target %s", n.Left.Type)
+	}
+}
+
+// propKey returns the name of the property accessed by the member expression.
+func (i *Interpreter) propKey(n *ast.MemberExpr, scope *env) (string, error) {
+	if !n.Computed {
+		return identName(n.Prop), nil
+	}
+
+	v, err := i.eval(n.Prop, scope)
+	if err != nil {
+		return "", err
+	}
+	return value.ToString(v), nil
+}
+
+func (i *Interpreter) evalCallExpr(node ast.Node, n *ast.CallExpr, scope *env) (Value, error) {
+	var callee Value
+	if n.Callee.Type == ast.SuperCallType {
+		var err error
+		if callee, err = superMethod(n.Callee, scope); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if callee, err = i.eval(n.Callee, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	args, err := i.evalArgs(n.Args, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.call(node, callee, args)
+}
+
+// superMethod returns the method of the parent class with the same name as
+// the method being evaluated, bound to the current instance.
+func superMethod(node ast.Node, scope *env) (Value, error) {
+	fn := scope.function()
+	if fn == nil || fn.class == nil {
+		return nil, errorf(node, "'super' outside of method")
+	}
+	if fn.class.Super == nil {
+		return nil, errorf(node, "class %s has no parent class", fn.class.Name)
+	}
+
+	method := fn.class.Super.FindMethod(fn.Name)
+	if method == nil {
+		return nil, errorf(node, "class %s has no method %s", fn.class.Super.Name, fn.Name)
+	}
+
+	this, _ := scope.lookup("this")
+	instance, ok := this.(*value.Instance)
+	if !ok {
+		return nil, errorf(node, "'super' outside of method")
+	}
+	return method.Bind(instance), nil
+}
+
+func (i *Interpreter) evalNewExpr(node ast.Node, n *ast.NewExpr, scope *env) (Value, error) {
+	callee, err := i.eval(n.Callee, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	class, ok := callee.(*value.Class)
+	if !ok {
+		return nil, errorf(n.Callee, "%s is not a class", value.TypeName(callee))
+	}
+
+	args, err := i.evalArgs(n.Args, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := value.NewInstance(class)
+	if ctor := class.FindMethod("constructor"); ctor != nil {
+		if _, err := i.call(node, ctor.Bind(instance), args); err != nil {
+			return nil, err
+		}
+	}
+
+	return instance, nil
+}
+
+func (i *Interpreter) evalArgs(nodes []ast.Node, scope *env) ([]Value, error) {
+	args := make([]Value, len(nodes))
+	for k, arg := range nodes {
+		var err error
+		if args[k], err = i.eval(arg, scope); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func (i *Interpreter) call(node ast.Node, callee Value, args []Value) (Value, error) {
+	switch fn := callee.(type) {
+	case *value.Builtin:
+		v, err := fn.Fn(args)
+		if err != nil {
+			return nil, errorf(node, "%s: %s", fn.Name, err)
+		}
+		return v, nil
+	case *Function:
+		return i.callFunction(node, fn, args)
+	case *value.Class:
+		return nil, errorf(node, "class %s can't be called without 'new'", fn.Name)
+	default:
+		return nil, errorf(node, "%s is not a function", value.TypeName(callee))
+	}
+}
+
+func (i *Interpreter) callFunction(node ast.Node, fn *Function, args []Value) (Value, error) {
+	if i.depth >= maxCallDepth {
+		return nil, errorf(node, "maximum call depth exceeded")
+	}
+	i.depth++
+	defer func() { i.depth-- }()
+
+	scope := newEnv(fn.closure)
+	scope.fn = fn
+	for k, param := range fn.params {
+		var arg Value
+		if k < len(args) {
+			arg = args[k]
+		}
+		scope.define(param, arg)
+	}
+
+	block, ok := fn.body.Fields.(*ast.BlockStmt)
+	if !ok {
+		return i.eval(fn.body, scope)
+	}
+	err := i.execList(block.Body, scope)
+	if ret, ok := err.(*returnSignal); ok {
+		return ret.value, nil
+	}
+	return nil, err
+}
+
+func identName(n ast.Node) string {
+	return n.Fields.(*ast.Identifier).Name
+}
+
+// labelName returns the name of the label of the break or continue
+// statement, it is empty if there is none.
+func labelName(n ast.Node) string {
+	if n == nil {
+		return ""
+	}
+	return identName(n)
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func errorf(node ast.Node, format string, args ...interface{}) error {
+	return &RuntimeError{
+		Loc: node.Loc,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// errorAt locates the error returned by the value package at the node, nil
+// is returned as it is.
+func errorAt(node ast.Node, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errorf(node, "%s", err)
+}
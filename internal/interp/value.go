@@ -0,0 +1,110 @@
+package interp
+
+import (
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/value"
+)
+
+// Value is a runtime value of the program, see value.Value. The user
+// defined functions and methods are *Function.
+type Value = value.Value
+
+type Function struct {
+	Name    string
+	params  []string
+	body    ast.Node
+	closure *env
+	// class is the class the method is defined in, nil for functions
+	class *value.Class
+	// method is set for the methods of the classes and the object literals,
+	// they are bound to the instance or the object they are read from
+	method bool
+	// this is the instance or the object the method is bound to
+	this Value
+}
+
+// bind returns a copy of the method with 'this' set to the instance or the
+// object.
+func (f *Function) bind(this Value) *Function {
+	scope := newEnv(f.closure)
+	scope.define("this", this)
+
+	bound := *f
+	bound.closure = scope
+	bound.this = this
+	return &bound
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<function>"
+	}
+	return "<function " + f.Name + ">"
+}
+
+func (f *Function) Bind(receiver Value) value.Function {
+	if !f.method || f.this != nil {
+		return f
+	}
+	return f.bind(receiver)
+}
+
+func (f *Function) Equal(other value.Function) bool {
+	g, ok := other.(*Function)
+	if !ok {
+		return false
+	}
+	// Bound methods are copies, compare what they were made of.
+	if f.this != nil {
+		return f.this == g.this && f.class == g.class && f.body == g.body
+	}
+	return f == g
+}
+
+type env struct {
+	vars   map[string]Value
+	parent *env
+	// fn is the function whose body is evaluated in this scope
+	fn *Function
+}
+
+func newEnv(parent *env) *env {
+	return &env{
+		vars:   map[string]Value{},
+		parent: parent,
+	}
+}
+
+func (e *env) define(name string, v Value) {
+	e.vars[name] = v
+}
+
+func (e *env) lookup(name string) (Value, bool) {
+	for s := e; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (e *env) assign(name string, v Value) bool {
+	for s := e; s != nil; s = s.parent {
+		if _, ok := s.vars[name]; ok {
+			s.vars[name] = v
+			return true
+		}
+	}
+	return false
+}
+
+// function returns the innermost function being evaluated, nil at the top
+// level.
+func (e *env) function() *Function {
+	for s := e; s != nil; s = s.parent {
+		if s.fn != nil {
+			return s.fn
+		}
+	}
+	return nil
+}
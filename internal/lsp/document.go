@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/resolver"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+// document is an open text document with the result of parsing it.
+type document struct {
+	uri     string
+	version int
+	text    string
+	// lineStarts are the offsets of the beginnings of the lines
+	lineStarts []int
+
+	program ast.Node
+	errs    parser.ErrorList
+
+	info        *resolver.Info
+	resolveErrs resolver.ErrorList
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version}
+	d.setText(text)
+	return d
+}
+
+// setText replaces the text of the document, parses and resolves it again.
+func (d *document) setText(text string) {
+	d.text = text
+	d.lineStarts = computeLineStarts(text)
+
+	var b ast.Builder
+	p := parser.NewParser(tokenizer.NewScanner(text), b)
+	p.KeepComments()
+	d.program, d.errs = p.ParseRecover()
+	d.info, d.resolveErrs = resolver.Resolve(d.program)
+}
+
+// applyChanges applies the changes to the text in order and parses the
+// result once.
+func (d *document) applyChanges(changes []TextDocumentContentChangeEvent) error {
+	text := d.text
+	lineStarts := d.lineStarts
+
+	for _, c := range changes {
+		if c.Range == nil {
+			text = c.Text
+		} else {
+			start, end := offsetOf(text, lineStarts, c.Range.Start), offsetOf(text, lineStarts, c.Range.End)
+			if start > end {
+				return errors.New("invalid range of the change")
+			}
+			text = text[:start] + c.Text + text[end:]
+		}
+		lineStarts = computeLineStarts(text)
+	}
+
+	d.setText(text)
+	return nil
+}
+
+func computeLineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// offset converts the LSP position to the byte offset in the text.
+func (d *document) offset(pos Position) int {
+	return offsetOf(d.text, d.lineStarts, pos)
+}
+
+func offsetOf(text string, lineStarts []int, pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(lineStarts) {
+		return len(text)
+	}
+
+	offset := lineStarts[pos.Line]
+	for units := 0; units < pos.Character && offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if r == '\n' {
+			break
+		}
+		units += utf16.RuneLen(r)
+		offset += size
+	}
+
+	return offset
+}
+
+// position converts the byte offset in the text to the LSP position.
+func (d *document) position(offset int) Position {
+	line := sort.Search(len(d.lineStarts), func(i int) bool {
+		return d.lineStarts[i] > offset
+	}) - 1
+
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16.RuneLen(r)
+	}
+
+	return Position{Line: line, Character: character}
+}
+
+func (d *document) rangeOf(loc ast.Loc) Range {
+	return Range{
+		Start: d.position(loc.Start.Offset),
+		End:   d.position(loc.End.Offset),
+	}
+}
@@ -0,0 +1,311 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+)
+
+const codeServerNotInitialized = -32002
+
+// Server is a Language Server Protocol server communicating over a pair of
+// streams, stdin and stdout of the process usually. It keeps the open
+// documents parsed and resolved and publishes their errors as diagnostics.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	initialized bool
+	shutdown    bool
+	docs        map[string]*document
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+type handlerFunc func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handlerFunc{
+	"initialize":                  (*Server).initialize,
+	"initialized":                 (*Server).ignore,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/didOpen":        (*Server).didOpen,
+	"textDocument/didChange":      (*Server).didChange,
+	"textDocument/didClose":       (*Server).didClose,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/foldingRange":   (*Server).foldingRange,
+}
+
+// Run serves the client until the exit notification or the end of the
+// input. It returns an error if the client exits without shutting the
+// server down first.
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(nil, &ResponseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(&req)
+		if req.isNotification() {
+			if err != nil {
+				log.Printf("%s: %s", req.Method, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) {
+				respErr = &ResponseError{Code: codeInternalError, Message: err.Error()}
+			}
+			err = s.replyError(req.ID, respErr)
+		} else {
+			err = writeMessage(s.out, &response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, error) {
+	handler, ok := handlers[req.Method]
+	if !ok {
+		if req.isNotification() {
+			// Notifications the server doesn't know are to be ignored
+			return nil, nil
+		}
+		return nil, &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if !s.initialized && req.Method != "initialize" {
+		return nil, &ResponseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+	if s.shutdown {
+		return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	return handler(s, req.Params)
+}
+
+func (s *Server) replyError(id json.RawMessage, respErr *ResponseError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return writeMessage(s.out, &errorResponse{JSONRPC: "2.0", ID: id, Error: respErr})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, &notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.initialized = true
+
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       SyncIncremental,
+			DocumentSymbolProvider: true,
+			DefinitionProvider:     true,
+			HoverProvider:          true,
+			FoldingRangeProvider:   true,
+		},
+		ServerInfo: ServerInfo{Name: "parser-from-scratch"},
+	}, nil
+}
+
+func (s *Server) ignore(json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	s.docs[d.uri] = d
+
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.applyChanges(p.ContentChanges); err != nil {
+		return nil, err
+	}
+	d.version = p.TextDocument.Version
+
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.docs, p.TextDocument.URI)
+
+	// Clear the diagnostics of the closed document
+	return nil, s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) publishDiagnostics(d *document) error {
+	return s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: diagnostics(d),
+	})
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := documentSymbols(d, d.program, false)
+	if symbols == nil {
+		symbols = []DocumentSymbol{}
+	}
+	return symbols, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	ident := identAt(d.program, d.offset(p.Position))
+	if ident == nil {
+		return nil, nil
+	}
+	obj := d.info.ObjectOf(ident)
+	if obj == nil || obj.Ident == nil {
+		return nil, nil
+	}
+
+	return &Location{URI: d.uri, Range: d.rangeOf(obj.Ident.Loc)}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	ident := identAt(d.program, d.offset(p.Position))
+	if ident == nil {
+		return nil, nil
+	}
+	obj := d.info.ObjectOf(ident)
+	if obj == nil {
+		return nil, nil
+	}
+	text := "```\n" + signature(obj) + "\n```"
+	if doc := docOf(d.program, obj); doc != "" {
+		text += "\n\n" + doc
+	}
+
+	identRange := d.rangeOf(ident.Loc)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    &identRange,
+	}, nil
+}
+
+func (s *Server) foldingRange(params json.RawMessage) (interface{}, error) {
+	var p FoldingRangeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	return foldingRanges(d), nil
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: "unknown document: " + uri}
+	}
+	return d, nil
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
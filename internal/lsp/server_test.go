@@ -0,0 +1,345 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURI = "file:///test.txt"
+
+const testSource = `class Point {
+	def constructor(x) {
+		this.x = x;
+	}
+}
+
+def make(x) {
+	let p = new Point(x);
+	return p;
+}
+make("ü")`
+
+func TestServer(t *testing.T) {
+	c := startServer(t)
+
+	var initResult InitializeResult
+	c.call("initialize", &InitializeParams{RootURI: "file:///"}, &initResult)
+	assert.Equal(t, SyncIncremental, initResult.Capabilities.TextDocumentSync)
+	c.notify("initialized", struct{}{})
+
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "parser", Version: 1, Text: testSource},
+	})
+	assert.Equal(t, &PublishDiagnosticsParams{
+		URI:     testURI,
+		Version: 1,
+		Diagnostics: []Diagnostic{{
+			Range:    Range{Start: Position{Line: 10, Character: 9}, End: Position{Line: 10, Character: 9}},
+			Severity: SeverityError,
+			Source:   "parser",
+			Message:  `unexpected end of input, expected: ";"`,
+		}},
+	}, c.diagnostics())
+
+	var symbols []DocumentSymbol
+	c.call("textDocument/documentSymbol", &DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}, &symbols)
+	assert.Equal(t, []DocumentSymbol{
+		{
+			Name:           "Point",
+			Detail:         "class Point",
+			Kind:           SymbolClass,
+			Range:          lineRange(0, 0, 4, 1),
+			SelectionRange: lineRange(0, 6, 0, 11),
+			Children: []DocumentSymbol{{
+				Name:           "constructor",
+				Detail:         "(method) def constructor(x)",
+				Kind:           SymbolMethod,
+				Range:          lineRange(1, 1, 3, 2),
+				SelectionRange: lineRange(1, 5, 1, 16),
+			}},
+		}, {
+			Name:           "make",
+			Detail:         "def make(x)",
+			Kind:           SymbolFunction,
+			Range:          lineRange(6, 0, 9, 1),
+			SelectionRange: lineRange(6, 4, 6, 8),
+		},
+	}, symbols)
+
+	var loc *Location
+	c.call("textDocument/definition", position(7, 14), &loc)
+	assert.Equal(t, &Location{URI: testURI, Range: lineRange(0, 6, 0, 11)}, loc, "class")
+
+	loc = nil
+	c.call("textDocument/definition", position(7, 20), &loc)
+	assert.Equal(t, &Location{URI: testURI, Range: lineRange(6, 9, 6, 10)}, loc, "parameter")
+
+	loc = nil
+	c.call("textDocument/definition", position(2, 8), &loc)
+	assert.Nil(t, loc, "property")
+
+	var hover *Hover
+	c.call("textDocument/hover", position(8, 9), &hover)
+	assert.Equal(t, &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```\nlet p\n```"},
+		Range:    &Range{Start: Position{Line: 8, Character: 8}, End: Position{Line: 8, Character: 9}},
+	}, hover)
+
+	hover = nil
+	c.call("textDocument/hover", position(7, 14), &hover)
+	require.NotNil(t, hover)
+	assert.Equal(t, "```\nclass Point\n```", hover.Contents.Value)
+
+	var folds []FoldingRange
+	c.call("textDocument/foldingRange", &FoldingRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}, &folds)
+	assert.Equal(t, []FoldingRange{
+		{StartLine: 0, EndLine: 3, Kind: "region"},
+		{StartLine: 1, EndLine: 2, Kind: "region"},
+		{StartLine: 6, EndLine: 8, Kind: "region"},
+	}, folds)
+
+	// "ü" is a single UTF-16 code unit but two bytes
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Range: &Range{Start: Position{Line: 10, Character: 9}, End: Position{Line: 10, Character: 9}}, Text: ";"},
+			{Range: &Range{Start: Position{Line: 10, Character: 6}, End: Position{Line: 10, Character: 7}}, Text: "x"},
+		},
+	})
+	assert.Equal(t, &PublishDiagnosticsParams{URI: testURI, Version: 2, Diagnostics: []Diagnostic{}}, c.diagnostics())
+	assert.Equal(t, "make(\"x\");", lastLine(c.server.docs[testURI].text))
+
+	respErr := c.callError("textDocument/unknown", struct{}{})
+	assert.Equal(t, &ResponseError{Code: codeMethodNotFound, Message: "method not found: textDocument/unknown"}, respErr)
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	require.NoError(t, <-c.done)
+}
+
+func TestServer_HoverDoc(t *testing.T) {
+	c := startServer(t)
+	c.call("initialize", &InitializeParams{RootURI: "file:///"}, nil)
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "parser", Version: 1, Text: `// answer is the answer.
+let answer = 42;
+
+/* twice doubles x. */
+def twice(x) { return x * 2; }
+twice(answer);`},
+	})
+	assert.Empty(t, c.diagnostics().Diagnostics)
+
+	for _, tc := range []struct {
+		pos  *TextDocumentPositionParams
+		want string
+	}{
+		{position(5, 1), "```\ndef twice(x)\n```\n\n twice doubles x."},
+		{position(5, 8), "```\nlet answer\n```\n\nanswer is the answer."},
+		{position(4, 10), "```\n(parameter) x\n```"},
+	} {
+		var hover *Hover
+		c.call("textDocument/hover", tc.pos, &hover)
+		require.NotNil(t, hover)
+		assert.Equal(t, tc.want, hover.Contents.Value)
+	}
+}
+
+func TestServer_NotInitialized(t *testing.T) {
+	c := startServer(t)
+
+	respErr := c.callError("textDocument/hover", position(0, 0))
+	assert.Equal(t, codeServerNotInitialized, respErr.Code)
+
+	c.notify("exit", nil)
+	assert.EqualError(t, <-c.done, "exit without shutdown")
+}
+
+func TestReadMessage_Error(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr string
+	}{
+		{
+			in:      "Content-Type: text/plain\r\n\r\n{}",
+			wantErr: "missing Content-Length header",
+		}, {
+			in:      "Content-Length: x\r\n\r\n{}",
+			wantErr: `invalid Content-Length header: strconv.Atoi: parsing "x": invalid syntax`,
+		}, {
+			in:      "Content-Length: -1\r\n\r\n{}",
+			wantErr: "invalid Content-Length header: -1 is out of range [0, 67108864]",
+		}, {
+			in:      "Content-Length: 99999999999\r\n\r\n{}",
+			wantErr: "invalid Content-Length header: 99999999999 is out of range [0, 67108864]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			_, err := readMessage(bufio.NewReader(strings.NewReader(tc.in)))
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestDocument_Offset(t *testing.T) {
+	d := newDocument(testURI, 1, "a𝄞b\nü\n")
+
+	for _, tc := range []struct {
+		pos    Position
+		offset int
+	}{
+		{Position{0, 0}, 0},
+		{Position{0, 1}, 1},
+		{Position{0, 3}, 5},
+		{Position{0, 4}, 6},
+		{Position{0, 10}, 6},
+		{Position{1, 1}, 9},
+		{Position{2, 0}, 10},
+		{Position{5, 0}, 10},
+	} {
+		assert.Equal(t, tc.offset, d.offset(tc.pos), "%v", tc.pos)
+	}
+
+	assert.Equal(t, Position{0, 3}, d.position(5))
+	assert.Equal(t, Position{1, 1}, d.position(9))
+}
+
+type testClient struct {
+	t      *testing.T
+	server *Server
+	in     io.Writer
+	out    *bufio.Reader
+	done   chan error
+	nextID int
+
+	// notifications are the ones received while waiting for a response
+	notifications []json.RawMessage
+}
+
+func startServer(t *testing.T) *testClient {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	c := &testClient{
+		t:      t,
+		server: NewServer(serverR, serverW),
+		in:     clientW,
+		out:    bufio.NewReader(clientR),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		c.done <- c.server.Run()
+		_ = serverW.Close()
+	}()
+	t.Cleanup(func() {
+		_ = clientW.Close()
+	})
+
+	return c
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	require.NoError(c.t, writeMessage(c.in, &notification{JSONRPC: "2.0", Method: method, Params: params}))
+}
+
+func (c *testClient) call(method string, params interface{}, result interface{}) {
+	resp := c.roundTrip(method, params)
+	require.Nil(c.t, resp.Error, method)
+	if result != nil {
+		require.NoError(c.t, json.Unmarshal(resp.Result, result))
+	}
+}
+
+func (c *testClient) callError(method string, params interface{}) *ResponseError {
+	resp := c.roundTrip(method, params)
+	require.NotNil(c.t, resp.Error, method)
+	return resp.Error
+}
+
+type testResponse struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func (c *testClient) roundTrip(method string, params interface{}) *testResponse {
+	c.nextID++
+	id, _ := json.Marshal(c.nextID)
+	require.NoError(c.t, writeMessage(c.in, &struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  interface{}     `json:"params"`
+	}{"2.0", id, method, params}))
+
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg.Params)
+			continue
+		}
+		require.Equal(c.t, c.nextID, *msg.ID)
+		return msg
+	}
+}
+
+// diagnostics returns the next diagnostics published by the server.
+func (c *testClient) diagnostics() *PublishDiagnosticsParams {
+	var params PublishDiagnosticsParams
+	if len(c.notifications) > 0 {
+		require.NoError(c.t, json.Unmarshal(c.notifications[0], &params))
+		c.notifications = c.notifications[1:]
+		return &params
+	}
+
+	msg := c.read()
+	require.Equal(c.t, "textDocument/publishDiagnostics", msg.Method)
+	require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+	return &params
+}
+
+func (c *testClient) read() *testResponse {
+	body, err := readMessage(c.out)
+	require.NoError(c.t, err)
+
+	var msg testResponse
+	require.NoError(c.t, json.Unmarshal(body, &msg))
+	return &msg
+}
+
+func position(line, character int) *TextDocumentPositionParams {
+	return &TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func lineRange(startLine, startChar, endLine, endChar int) Range {
+	return Range{
+		Start: Position{Line: startLine, Character: startChar},
+		End:   Position{Line: endLine, Character: endChar},
+	}
+}
+
+func lastLine(s string) string {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == '\n' {
+			return s[i+1:]
+		}
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/resolver"
+)
+
+// identAt returns the identifier at the offset or nil if there is none.
+func identAt(program ast.Node, offset int) ast.Node {
+	var ident ast.Node
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil || ident != nil || !n.Loc.IsValid() {
+			return false
+		}
+		if offset < n.Loc.Start.Offset || n.Loc.End.Offset < offset {
+			return false
+		}
+		if n.Type == ast.IdentifierType {
+			ident = n
+		}
+		return true
+	})
+	return ident
+}
+
+// signature returns the one line description of the object.
+func signature(obj *resolver.Object) string {
+	switch obj.Kind {
+	case resolver.ParamObj:
+		return "(parameter) " + obj.Name
+	case resolver.VarObj:
+		return "let " + obj.Name
+	case resolver.FuncObj, resolver.MethodObj:
+		var paramNodes []ast.Node
+		switch f := obj.Decl.Fields.(type) {
+		case *ast.FuncDecl:
+			paramNodes = f.Params
+		case *ast.FuncExpr:
+			paramNodes = f.Params
+		}
+		params := make([]string, len(paramNodes))
+		for i, p := range paramNodes {
+			params[i] = identName(p)
+		}
+		prefix := "def "
+		if obj.Kind == resolver.MethodObj {
+			prefix = "(method) def "
+		}
+		return prefix + obj.Name + "(" + strings.Join(params, ", ") + ")"
+	case resolver.ClassObj:
+		f := obj.Decl.Fields.(*ast.ClassDecl)
+		if f.Super != nil {
+			return "class " + obj.Name + " extends " + identName(f.Super)
+		}
+		return "class " + obj.Name
+	case resolver.BuiltinObj:
+		return "(builtin) def " + obj.Name + "(...args)"
+	default:
+		return obj.Name
+	}
+}
+
+// docOf returns the text of the doc comment of the object. The doc of a
+// variable is the one of the VarStmt declaring it.
+func docOf(program ast.Node, obj *resolver.Object) string {
+	if obj.Decl == nil {
+		return ""
+	}
+
+	switch f := obj.Decl.Fields.(type) {
+	case *ast.FuncDecl:
+		return f.Doc.Text()
+	case *ast.ClassDecl:
+		return f.Doc.Text()
+	}
+
+	var doc string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil || doc != "" {
+			return false
+		}
+		if stmt, ok := n.Fields.(*ast.VarStmt); ok {
+			for _, decl := range stmt.Decls {
+				if decl == obj.Decl {
+					doc = stmt.Doc.Text()
+				}
+			}
+			return false
+		}
+		return true
+	})
+	return doc
+}
+
+// documentSymbols returns the functions and classes declared in the node
+// and all the nested ones as their children. The functions declared in the
+// class body are methods.
+func documentSymbols(d *document, n ast.Node, inClass bool) []DocumentSymbol {
+	var symbols []DocumentSymbol
+
+	ast.Inspect(n, func(c ast.Node) bool {
+		if c == nil || c == n {
+			return true
+		}
+
+		var sym DocumentSymbol
+		switch f := c.Fields.(type) {
+		case *ast.FuncDecl:
+			sym = DocumentSymbol{
+				Name:           identName(f.Name),
+				Kind:           SymbolFunction,
+				SelectionRange: d.rangeOf(f.Name.Loc),
+			}
+			kind := resolver.FuncObj
+			if inClass {
+				kind = resolver.MethodObj
+				sym.Kind = SymbolMethod
+			}
+			sym.Detail = signature(&resolver.Object{Kind: kind, Name: sym.Name, Ident: f.Name, Decl: c})
+			sym.Children = documentSymbols(d, f.Body, false)
+		case *ast.ClassDecl:
+			sym = DocumentSymbol{
+				Name:           identName(f.ID),
+				Detail:         signature(&resolver.Object{Kind: resolver.ClassObj, Name: identName(f.ID), Ident: f.ID, Decl: c}),
+				Kind:           SymbolClass,
+				SelectionRange: d.rangeOf(f.ID.Loc),
+			}
+			sym.Children = documentSymbols(d, f.Body, true)
+		default:
+			return true
+		}
+
+		sym.Range = d.rangeOf(c.Loc)
+		symbols = append(symbols, sym)
+		return false
+	})
+
+	return symbols
+}
+
+// foldingRanges returns the ranges of the blocks spanning several lines,
+// the line with the closing brace is left unfolded.
+func foldingRanges(d *document) []FoldingRange {
+	ranges := []FoldingRange{}
+
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		if n == nil || n.Type != ast.BlockStmtType || !n.Loc.IsValid() {
+			return true
+		}
+
+		start := d.position(n.Loc.Start.Offset).Line
+		end := d.position(n.Loc.End.Offset).Line - 1
+		if end > start {
+			ranges = append(ranges, FoldingRange{StartLine: start, EndLine: end, Kind: "region"})
+		}
+		return true
+	})
+
+	return ranges
+}
+
+// diagnostics converts the syntax and the resolution errors of the
+// document.
+func diagnostics(d *document) []Diagnostic {
+	result := []Diagnostic{}
+
+	for _, err := range d.errs {
+		result = append(result, Diagnostic{
+			Range:    d.rangeOf(err.Loc),
+			Severity: SeverityError,
+			Source:   "parser",
+			Message:  err.Err.Error(),
+		})
+	}
+
+	for _, err := range d.resolveErrs {
+		severity := SeverityError
+		if err.Warning {
+			severity = SeverityWarning
+		}
+		result = append(result, Diagnostic{
+			Range:    d.rangeOf(err.Loc),
+			Severity: severity,
+			Source:   "resolver",
+			Message:  err.Err.Error(),
+		})
+	}
+
+	return result
+}
+
+func identName(n ast.Node) string {
+	if id, ok := n.Fields.(*ast.Identifier); ok {
+		return id.Name
+	}
+	return ""
+}
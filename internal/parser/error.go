@@ -16,6 +16,25 @@ func (e *ErrUnknownLiteral) Error() string {
 	return fmt.Sprintf("unknown literal type %s: \"%s\"", e.Type, e.Value)
 }
 
+type ErrNumericOverflow struct {
+	Raw  string
+	Kind ast.NumericKind
+}
+
+func (e *ErrNumericOverflow) Error() string {
+	return fmt.Sprintf("numeric literal %s overflows %s64", e.Raw, e.Kind)
+}
+
+type ErrInvalidEscape struct {
+	Escape string
+	// Offset is the offset of the escape sequence in the string literal
+	Offset int
+}
+
+func (e *ErrInvalidEscape) Error() string {
+	return fmt.Sprintf("invalid escape sequence %s", e.Escape)
+}
+
 type ErrUnexpectedEndOfInput struct {
 	Type tokenizer.TokenType
 }
@@ -71,5 +90,103 @@ type ErrInvalidLvalue struct {
 }
 
 func (e *ErrInvalidLvalue) Error() string {
-	return fmt.Sprintf("invalid lvalue in assignment: %+v", e.Node)
+	return fmt.Sprintf("invalid lvalue in assignment: %s", e.Node.Type)
+}
+
+type ErrInvalidParam struct {
+	Node ast.Node
+}
+
+func (e *ErrInvalidParam) Error() string {
+	return fmt.Sprintf("invalid arrow function parameter: %s", e.Node.Type)
+}
+
+type ErrBreakOutsideLoop struct{}
+
+func (e *ErrBreakOutsideLoop) Error() string {
+	return "break outside of loop or switch"
+}
+
+type ErrContinueOutsideLoop struct{}
+
+func (e *ErrContinueOutsideLoop) Error() string {
+	return "continue outside of loop"
+}
+
+type ErrUndefinedLabel struct {
+	Label string
+}
+
+func (e *ErrUndefinedLabel) Error() string {
+	return fmt.Sprintf("undefined label: %s", e.Label)
+}
+
+// ErrNotLoopLabel is the continue statement referring to the label of a
+// statement which is not a loop.
+type ErrNotLoopLabel struct {
+	Label string
+}
+
+func (e *ErrNotLoopLabel) Error() string {
+	return fmt.Sprintf("continue label %s does not denote a loop", e.Label)
+}
+
+type ErrDuplicateLabel struct {
+	Label string
+}
+
+func (e *ErrDuplicateLabel) Error() string {
+	return fmt.Sprintf("label %s already defined", e.Label)
+}
+
+type ErrMissingCatchOrFinally struct{}
+
+func (e *ErrMissingCatchOrFinally) Error() string {
+	return "missing catch or finally after try"
+}
+
+type ErrDuplicateDefault struct{}
+
+func (e *ErrDuplicateDefault) Error() string {
+	return "multiple default clauses in switch"
+}
+
+// Error is a syntax error tied to the span of the source code where it was
+// found.
+type Error struct {
+	Loc ast.Loc
+	Err error
+}
+
+func (e *Error) Error() string {
+	// The errors of reading the input may happen before the first token
+	if !e.Loc.IsValid() {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Loc.Start.Line, e.Loc.Start.Column, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList is a list of syntax errors collected by Parser.ParseRecover.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns nil when the list is empty and the list itself otherwise.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
 }
@@ -1,20 +1,51 @@
 package parser
 
 import (
+	"errors"
 	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
 )
 
+// Tokenizer produces the tokens of the source code, both tokenizer.Scanner
+// and tokenizer.Tokenizer implement it.
 type Tokenizer interface {
-	NextToken() (*tokenizer.Token, error)
+	NextToken() (tokenizer.Token, error)
 }
 
 type Parser struct {
 	tokenizer Tokenizer
-	lookahead *tokenizer.Token
+	lookahead tokenizer.Token
+	prevEnd   tokenizer.Position
 	builder   ast.Builder
+
+	recovering bool
+	errors     ErrorList
+
+	keepComments bool
+	comments     []*ast.Comment
+	// leadComment is the comment group ending right above the lookahead
+	// token
+	leadComment *ast.CommentGroup
+
+	// labels are the labels of the statements enclosing the one being
+	// parsed, loops and switches are the numbers of the enclosing loops and
+	// switch statements, the function bodies start with none of them
+	labels   []stmtLabel
+	loops    int
+	switches int
+}
+
+// stmtLabel is the label of the statement starting at the offset stmt,
+// the statements labeled with several labels share the offset.
+type stmtLabel struct {
+	name string
+	stmt int
+	loop bool
 }
 
 func NewParser(t Tokenizer, b ast.Builder) *Parser {
@@ -25,49 +56,127 @@ func NewParser(t Tokenizer, b ast.Builder) *Parser {
 }
 
 func (p *Parser) Parse() (ast.Node, error) {
-	var err error
-	p.lookahead, err = p.tokenizer.NextToken()
-	if err != nil {
+	if err := p.next(); err != nil {
 		return nil, err
 	}
 
 	return p.program()
 }
 
+// KeepComments makes the parser collect the comments into the Comments of
+// the ast.Program, otherwise they are dropped.
+func (p *Parser) KeepComments() {
+	p.keepComments = true
+}
+
+// ParseRecover parses the program without stopping at the first syntax error.
+// A statement which fails to parse is replaced with ast.BadStmt and parsing
+// resumes at the next statement boundary. The returned program is always
+// non-nil, the list contains every error found.
+func (p *Parser) ParseRecover() (ast.Node, ErrorList) {
+	p.recovering = true
+	p.errors = nil
+
+	_ = p.next()
+	node, err := p.program()
+	if err != nil {
+		p.addError(err)
+	}
+
+	return node, p.errors
+}
+
 // Program
 //   : StatementList
 //   ;
 func (p *Parser) program() (ast.Node, error) {
-	body, err := p.stmtList(tokenizer.EOF)
-	if err != nil {
-		return nil, err
+	start := p.pos()
+
+	var body []ast.Node
+	if p.lookahead.Type != tokenizer.EOF {
+		var err error
+		body, err = p.stmtList(tokenizer.EOF)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return p.builder.Program(body...), nil
+	program := p.locate(p.builder.Program(body...), start)
+	program.Fields.(*ast.Program).Comments = p.comments
+
+	return program, nil
 }
 
 // StmtList
 //   : Stmt
 //   | StmtList Stmt
 //   ;
-func (p *Parser) stmtList(stopLookahead tokenizer.TokenType) ([]ast.Node, error) {
-	statement, err := p.stmt()
-	if err != nil {
-		return nil, err
-	}
-	statementList := []ast.Node{statement}
+func (p *Parser) stmtList(stopLookahead ...tokenizer.TokenType) ([]ast.Node, error) {
+	var statementList []ast.Node
 
-	for p.lookahead != nil && p.lookahead.Type != stopLookahead {
+	for {
+		start := p.pos()
 		statement, err := p.stmt()
 		if err != nil {
-			return nil, err
+			if !p.recovering {
+				return nil, err
+			}
+			statement = p.badStmt(start, err)
 		}
 		statementList = append(statementList, statement)
+
+		if p.lookahead.Type == tokenizer.EOF || p.lookaheadIn(stopLookahead) {
+			break
+		}
 	}
 
 	return statementList, nil
 }
 
+// badStmt records the error and skips tokens up to the next statement
+// boundary. The skipped source code is covered by the returned ast.BadStmt.
+func (p *Parser) badStmt(start ast.Position, err error) ast.Node {
+	p.addError(err)
+
+	// Make sure the parser moves forward even when the very first token of
+	// the statement is the one it has choked on. A stray '}' is a statement
+	// boundary on its own.
+	if p.lookahead.Start.Offset == start.Offset && p.lookahead.Type != tokenizer.EOF {
+		stray := p.lookahead.Type == tokenizer.CloseCurlyBrace
+		_ = p.next()
+		if stray {
+			return p.locate(p.builder.BadStmt(), start)
+		}
+	}
+
+	for {
+		switch p.lookahead.Type {
+		case tokenizer.EOF, tokenizer.CloseCurlyBrace:
+			return p.locate(p.builder.BadStmt(), start)
+		case tokenizer.Semicolon:
+			_ = p.next()
+			return p.locate(p.builder.BadStmt(), start)
+		case tokenizer.LetKeyword,
+			tokenizer.IfKeyword,
+			tokenizer.WhileKeyword,
+			tokenizer.DoKeyword,
+			tokenizer.ForKeyword,
+			tokenizer.DefKeyword,
+			tokenizer.ClassKeyword,
+			tokenizer.ReturnKeyword,
+			tokenizer.BreakKeyword,
+			tokenizer.ContinueKeyword,
+			tokenizer.TryKeyword,
+			tokenizer.ThrowKeyword,
+			tokenizer.SwitchKeyword,
+			tokenizer.CaseKeyword,
+			tokenizer.DefaultKeyword:
+			return p.locate(p.builder.BadStmt(), start)
+		}
+		_ = p.next()
+	}
+}
+
 // Stmt
 //   : ExprStmt
 //   | BlockStmt
@@ -77,6 +186,12 @@ func (p *Parser) stmtList(stopLookahead tokenizer.TokenType) ([]ast.Node, error)
 //   | IterStmt
 //   | FuncDecl
 //   | ReturnStmt
+//   | BreakStmt
+//   | ContinueStmt
+//   | LabeledStmt
+//   | TryStmt
+//   | ThrowStmt
+//   | SwitchStmt
 //   | ClassDecl
 //   ;
 func (p *Parser) stmt() (ast.Node, error) {
@@ -97,6 +212,16 @@ func (p *Parser) stmt() (ast.Node, error) {
 		return p.classDecl()
 	case tokenizer.ReturnKeyword:
 		return p.returnStmt()
+	case tokenizer.BreakKeyword:
+		return p.breakStmt()
+	case tokenizer.ContinueKeyword:
+		return p.continueStmt()
+	case tokenizer.TryKeyword:
+		return p.tryStmt()
+	case tokenizer.ThrowKeyword:
+		return p.throwStmt()
+	case tokenizer.SwitchKeyword:
+		return p.switchStmt()
 	default:
 		return p.exprStmt()
 	}
@@ -105,29 +230,37 @@ func (p *Parser) stmt() (ast.Node, error) {
 // ExprStmt
 //   : SeqExpr ';'
 //   ;
+//
+// The Identifier followed by ':' is the label of the LabeledStmt.
 func (p *Parser) exprStmt() (ast.Node, error) {
+	start := p.pos()
 	node, err := p.seqExpr()
 	if err != nil {
 		return nil, err
 	}
 
+	if p.lookahead.Type == tokenizer.Colon && node.Type == ast.IdentifierType && node.Loc.Start == start {
+		return p.labeledStmt(node)
+	}
+
 	if _, err := p.consume(tokenizer.Semicolon); err != nil {
 		return nil, err
 	}
 
-	return p.builder.ExprStmt(node), nil
+	return p.locate(p.builder.ExprStmt(node), start), nil
 }
 
 // BlockStmt
 //   : '{' OptStmtList '}'
 //   ;
 func (p *Parser) blockStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.OpenCurlyBrace); err != nil {
 		return nil, err
 	}
 
 	var body []ast.Node
-	if p.lookahead.Type != tokenizer.CloseCurlyBrace {
+	if p.lookahead.Type != tokenizer.CloseCurlyBrace && p.lookahead.Type != tokenizer.EOF {
 		var err error
 		body, err = p.stmtList(tokenizer.CloseCurlyBrace)
 		if err != nil {
@@ -136,27 +269,33 @@ func (p *Parser) blockStmt() (ast.Node, error) {
 	}
 
 	if _, err := p.consume(tokenizer.CloseCurlyBrace); err != nil {
-		return nil, err
+		// An unclosed block at the end of input keeps its statements.
+		if !p.recovering || p.lookahead.Type != tokenizer.EOF {
+			return nil, err
+		}
+		p.addError(err)
 	}
 
-	return p.builder.BlockStmt(body...), nil
+	return p.locate(p.builder.BlockStmt(body...), start), nil
 }
 
 // EmptyStmt
 //   : ';'
 //   ;
 func (p *Parser) emptyStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.Semicolon); err != nil {
 		return nil, err
 	}
 
-	return p.builder.EmptyStmt(), nil
+	return p.locate(p.builder.EmptyStmt(), start), nil
 }
 
 // VarStmt
 //   : VarStmtInit ';'
 //   ;
 func (p *Parser) varStmt() (ast.Node, error) {
+	doc := p.leadComment
 	node, err := p.varStmtInit()
 	if err != nil {
 		return nil, err
@@ -166,13 +305,16 @@ func (p *Parser) varStmt() (ast.Node, error) {
 		return nil, err
 	}
 
-	return node, nil
+	node.Fields.(*ast.VarStmt).Doc = doc
+
+	return p.locate(node, node.Loc.Start), nil
 }
 
 // VarStmtInit
 //   : 'let' VarDeclList
 //   ;
 func (p *Parser) varStmtInit() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.LetKeyword); err != nil {
 		return nil, err
 	}
@@ -182,7 +324,7 @@ func (p *Parser) varStmtInit() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.VarStmt(declarations...), nil
+	return p.locate(p.builder.VarStmt(declarations...), start), nil
 }
 
 // IfStmt
@@ -190,6 +332,7 @@ func (p *Parser) varStmtInit() (ast.Node, error) {
 //   | 'if' '(' SeqExpr ')' Stmt 'else' Stmt
 //   ;
 func (p *Parser) ifStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.IfKeyword); err != nil {
 		return nil, err
 	}
@@ -223,7 +366,7 @@ func (p *Parser) ifStmt() (ast.Node, error) {
 		}
 	}
 
-	return p.builder.IfStmt(cond, cons, alt), nil
+	return p.locate(p.builder.IfStmt(cond, cons, alt), start), nil
 }
 
 // IterStmt
@@ -232,6 +375,12 @@ func (p *Parser) ifStmt() (ast.Node, error) {
 //   | ForStmt
 //   ;
 func (p *Parser) iterStmt() (ast.Node, error) {
+	for i := len(p.labels) - 1; i >= 0 && p.labels[i].stmt == p.pos().Offset; i-- {
+		p.labels[i].loop = true
+	}
+	p.loops++
+	defer func() { p.loops-- }()
+
 	switch p.lookahead.Type {
 	case tokenizer.WhileKeyword:
 		return p.whileStmt()
@@ -240,11 +389,7 @@ func (p *Parser) iterStmt() (ast.Node, error) {
 	case tokenizer.ForKeyword:
 		return p.forStmt()
 	default:
-		return nil, &ErrUnexpectedToken{
-			Type:         p.lookahead.Type,
-			ExpectedType: "Iteration",
-			Value:        p.lookahead.Value,
-		}
+		return nil, p.unexpected("Iteration")
 	}
 }
 
@@ -252,6 +397,7 @@ func (p *Parser) iterStmt() (ast.Node, error) {
 //   : 'def' Identifier '(' OptFormalParamList ')' BlockStmt
 //   ;
 func (p *Parser) funcDecl() (ast.Node, error) {
+	start, doc := p.pos(), p.leadComment
 	if _, err := p.consume(tokenizer.DefKeyword); err != nil {
 		return nil, err
 	}
@@ -261,6 +407,36 @@ func (p *Parser) funcDecl() (ast.Node, error) {
 		return nil, err
 	}
 
+	params, err := p.formalParams()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.funcBody()
+	if err != nil {
+		return nil, err
+	}
+
+	node := p.builder.FuncDecl(name, params, body)
+	node.Fields.(*ast.FuncDecl).Doc = doc
+
+	return p.locate(node, start), nil
+}
+
+// funcBody parses the body of the function, the break and continue
+// statements in it can't refer to the statements around the function.
+func (p *Parser) funcBody() (ast.Node, error) {
+	labels, loops, switches := p.labels, p.loops, p.switches
+	p.labels, p.loops, p.switches = nil, 0, 0
+	defer func() { p.labels, p.loops, p.switches = labels, loops, switches }()
+
+	return p.blockStmt()
+}
+
+// FormalParams
+//   : '(' OptFormalParamList ')'
+//   ;
+func (p *Parser) formalParams() ([]ast.Node, error) {
 	if _, err := p.consume(tokenizer.OpenParens); err != nil {
 		return nil, err
 	}
@@ -277,12 +453,7 @@ func (p *Parser) funcDecl() (ast.Node, error) {
 		return nil, err
 	}
 
-	body, err := p.blockStmt()
-	if err != nil {
-		return nil, err
-	}
-
-	return p.builder.FuncDecl(name, params, body), nil
+	return params, nil
 }
 
 // FormalParamList
@@ -313,6 +484,7 @@ func (p *Parser) formalParamList() ([]ast.Node, error) {
 //   : 'return' OptSeqExpr
 //   ;
 func (p *Parser) returnStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.ReturnKeyword); err != nil {
 		return nil, err
 	}
@@ -329,13 +501,312 @@ func (p *Parser) returnStmt() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.ReturnStmt(arg), nil
+	return p.locate(p.builder.ReturnStmt(arg), start), nil
+}
+
+// BreakStmt
+//   : 'break' OptIdentifier ';'
+//   ;
+func (p *Parser) breakStmt() (ast.Node, error) {
+	start := p.pos()
+	tok, err := p.consume(tokenizer.BreakKeyword)
+	if err != nil {
+		return nil, err
+	}
+
+	var label ast.Node
+	if p.lookahead.Type == tokenizer.Identifier {
+		if label, err = p.jumpLabel(false); err != nil {
+			return nil, err
+		}
+	} else if p.loops == 0 && p.switches == 0 {
+		return nil, tokenError(tok, &ErrBreakOutsideLoop{})
+	}
+
+	if _, err := p.consume(tokenizer.Semicolon); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.BreakStmt(label), start), nil
+}
+
+// ContinueStmt
+//   : 'continue' OptIdentifier ';'
+//   ;
+func (p *Parser) continueStmt() (ast.Node, error) {
+	start := p.pos()
+	tok, err := p.consume(tokenizer.ContinueKeyword)
+	if err != nil {
+		return nil, err
+	}
+
+	var label ast.Node
+	if p.lookahead.Type == tokenizer.Identifier {
+		if label, err = p.jumpLabel(true); err != nil {
+			return nil, err
+		}
+	} else if p.loops == 0 {
+		return nil, tokenError(tok, &ErrContinueOutsideLoop{})
+	}
+
+	if _, err := p.consume(tokenizer.Semicolon); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ContinueStmt(label), start), nil
+}
+
+// jumpLabel parses the label of the break or continue statement, it must
+// be the label of one of the enclosing statements. The continue statement
+// requires the statement to be a loop.
+func (p *Parser) jumpLabel(loop bool) (ast.Node, error) {
+	label, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	name := label.Fields.(*ast.Identifier).Name
+	l := p.findLabel(name)
+	switch {
+	case l == nil:
+		return nil, &Error{Loc: label.Loc, Err: &ErrUndefinedLabel{Label: name}}
+	case loop && !l.loop:
+		return nil, &Error{Loc: label.Loc, Err: &ErrNotLoopLabel{Label: name}}
+	}
+
+	return label, nil
+}
+
+// LabeledStmt
+//   : Identifier ':' Stmt
+//   ;
+func (p *Parser) labeledStmt(label ast.Node) (ast.Node, error) {
+	name := label.Fields.(*ast.Identifier).Name
+	if p.findLabel(name) != nil {
+		return nil, &Error{Loc: label.Loc, Err: &ErrDuplicateLabel{Label: name}}
+	}
+
+	if _, err := p.consume(tokenizer.Colon); err != nil {
+		return nil, err
+	}
+
+	// The statement starting with this label has the same body
+	stmt := p.pos().Offset
+	for i := len(p.labels) - 1; i >= 0 && p.labels[i].stmt == label.Loc.Start.Offset; i-- {
+		p.labels[i].stmt = stmt
+	}
+	p.labels = append(p.labels, stmtLabel{name: name, stmt: stmt})
+	defer func() { p.labels = p.labels[:len(p.labels)-1] }()
+
+	body, err := p.stmt()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.LabeledStmt(label, body), label.Loc.Start), nil
+}
+
+func (p *Parser) findLabel(name string) *stmtLabel {
+	for i := len(p.labels) - 1; i >= 0; i-- {
+		if p.labels[i].name == name {
+			return &p.labels[i]
+		}
+	}
+	return nil
+}
+
+// TryStmt
+//   : 'try' BlockStmt Catch
+//   | 'try' BlockStmt Finally
+//   | 'try' BlockStmt Catch Finally
+//   ;
+func (p *Parser) tryStmt() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.TryKeyword); err != nil {
+		return nil, err
+	}
+
+	block, err := p.blockStmt()
+	if err != nil {
+		return nil, err
+	}
+
+	var handler, finalizer ast.Node
+	if p.lookahead.Type == tokenizer.CatchKeyword {
+		if handler, err = p.catchClause(); err != nil {
+			return nil, err
+		}
+	}
+	if p.lookahead.Type == tokenizer.FinallyKeyword {
+		if finalizer, err = p.finallyClause(); err != nil {
+			return nil, err
+		}
+	}
+	if handler == nil && finalizer == nil {
+		return nil, tokenError(p.lookahead, &ErrMissingCatchOrFinally{})
+	}
+
+	return p.locate(p.builder.TryStmt(block, handler, finalizer), start), nil
+}
+
+// Catch
+//   : 'catch' '(' Identifier ')' BlockStmt
+//   ;
+func (p *Parser) catchClause() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.CatchKeyword); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.OpenParens); err != nil {
+		return nil, err
+	}
+
+	param, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.CloseParens); err != nil {
+		return nil, err
+	}
+
+	body, err := p.blockStmt()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.CatchClause(param, body), start), nil
+}
+
+// Finally
+//   : 'finally' BlockStmt
+//   ;
+func (p *Parser) finallyClause() (ast.Node, error) {
+	if _, err := p.consume(tokenizer.FinallyKeyword); err != nil {
+		return nil, err
+	}
+
+	return p.blockStmt()
+}
+
+// ThrowStmt
+//   : 'throw' SeqExpr ';'
+//   ;
+func (p *Parser) throwStmt() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.ThrowKeyword); err != nil {
+		return nil, err
+	}
+
+	arg, err := p.seqExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.Semicolon); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ThrowStmt(arg), start), nil
+}
+
+// SwitchStmt
+//   : 'switch' '(' SeqExpr ')' '{' OptCaseClauseList '}'
+//   ;
+func (p *Parser) switchStmt() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.SwitchKeyword); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.OpenParens); err != nil {
+		return nil, err
+	}
+
+	disc, err := p.seqExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.CloseParens); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.OpenCurlyBrace); err != nil {
+		return nil, err
+	}
+
+	p.switches++
+	defer func() { p.switches-- }()
+
+	var cases []ast.Node
+	hasDefault := false
+	for p.lookahead.Type != tokenizer.CloseCurlyBrace && p.lookahead.Type != tokenizer.EOF {
+		if p.lookahead.Type == tokenizer.DefaultKeyword {
+			if hasDefault {
+				return nil, tokenError(p.lookahead, &ErrDuplicateDefault{})
+			}
+			hasDefault = true
+		}
+
+		clause, err := p.caseClause()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, clause)
+	}
+
+	if _, err := p.consume(tokenizer.CloseCurlyBrace); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.SwitchStmt(disc, cases...), start), nil
+}
+
+// CaseClause
+//   : 'case' SeqExpr ':' OptStmtList
+//   | 'default' ':' OptStmtList
+//   ;
+func (p *Parser) caseClause() (ast.Node, error) {
+	start := p.pos()
+
+	var test ast.Node
+	if p.lookahead.Type == tokenizer.DefaultKeyword {
+		if _, err := p.consume(tokenizer.DefaultKeyword); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := p.consume(tokenizer.CaseKeyword); err != nil {
+			return nil, err
+		}
+		var err error
+		if test, err = p.seqExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(tokenizer.Colon); err != nil {
+		return nil, err
+	}
+
+	stops := []tokenizer.TokenType{tokenizer.CaseKeyword, tokenizer.DefaultKeyword, tokenizer.CloseCurlyBrace}
+	var body []ast.Node
+	if p.lookahead.Type != tokenizer.EOF && !p.lookaheadIn(stops) {
+		var err error
+		if body, err = p.stmtList(stops...); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.locate(p.builder.SwitchCase(test, body...), start), nil
 }
 
 // ClassDecl
 //   : 'class' Identifier OptClassExtends BlockStmt
 //   ;
 func (p *Parser) classDecl() (ast.Node, error) {
+	start, doc := p.pos(), p.leadComment
 	if _, err := p.consume(tokenizer.ClassKeyword); err != nil {
 		return nil, err
 	}
@@ -357,7 +828,10 @@ func (p *Parser) classDecl() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.ClassDecl(id, superClass, body), nil
+	node := p.builder.ClassDecl(id, superClass, body)
+	node.Fields.(*ast.ClassDecl).Doc = doc
+
+	return p.locate(node, start), nil
 }
 
 // ClassExtends
@@ -374,6 +848,7 @@ func (p *Parser) classExtends() (ast.Node, error) {
 //   : 'while' '(' SeqExpr ')' Stmt
 //   ;
 func (p *Parser) whileStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.WhileKeyword); err != nil {
 		return nil, err
 	}
@@ -396,13 +871,14 @@ func (p *Parser) whileStmt() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.WhileStmt(cond, body), nil
+	return p.locate(p.builder.WhileStmt(cond, body), start), nil
 }
 
 // DoWhileStmt
 //   : 'do' Stmt 'while' '(' SeqExpr ')' ';'
 //   ;
 func (p *Parser) doWhileStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.DoKeyword); err != nil {
 		return nil, err
 	}
@@ -433,13 +909,14 @@ func (p *Parser) doWhileStmt() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.DoWhileStmt(cond, body), nil
+	return p.locate(p.builder.DoWhileStmt(cond, body), start), nil
 }
 
 // ForStmt
 //   : 'for' '(' OptForStmtInit ';' OptSeqExpr ';' OptSeqExpr ')' Stmt
 //   ;
 func (p *Parser) forStmt() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.ForKeyword); err != nil {
 		return nil, err
 	}
@@ -486,7 +963,7 @@ func (p *Parser) forStmt() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.ForStmt(init, cond, step, body), nil
+	return p.locate(p.builder.ForStmt(init, cond, step, body), start), nil
 }
 
 // ForStmtInit
@@ -526,6 +1003,7 @@ func (p *Parser) varDeclList() ([]ast.Node, error) {
 //   : Identifier OptVarInit
 //   ;
 func (p *Parser) varDecl() (ast.Node, error) {
+	start := p.pos()
 	id, err := p.identifier()
 	if err != nil {
 		return nil, err
@@ -539,7 +1017,7 @@ func (p *Parser) varDecl() (ast.Node, error) {
 		}
 	}
 
-	return p.builder.VarDecl(id, init), nil
+	return p.locate(p.builder.VarDecl(id, init), start), nil
 }
 
 // VarInit
@@ -577,7 +1055,7 @@ func (p *Parser) seqExpr() (ast.Node, error) {
 	if len(body) == 1 {
 		return body[0], nil
 	}
-	return p.builder.SeqExpr(body...), nil
+	return p.locate(p.builder.SeqExpr(body...), body[0].Loc.Start), nil
 }
 
 // Expr
@@ -588,11 +1066,11 @@ func (p *Parser) expr() (ast.Node, error) {
 }
 
 // AssignExpr
-//   : EqualExpr
+//   : ConditionalExpr
 //   | LeftHandSideExpr AssignOp AssignExpr
 //   ;
 func (p *Parser) assignExpr() (ast.Node, error) {
-	left, err := p.logicalOrExpr()
+	left, err := p.conditionalExpr()
 	if err != nil {
 		return nil, err
 	}
@@ -609,9 +1087,9 @@ func (p *Parser) assignExpr() (ast.Node, error) {
 
 	op := ast.AssignOpFromString(opTok.Value)
 	if op == ast.InvalidAssignOp {
-		return nil, &ErrUnknownAssignOp{
+		return nil, tokenError(opTok, &ErrUnknownAssignOp{
 			Op: opTok.Value,
-		}
+		})
 	}
 
 	if err := checkValidAssignTarget(left); err != nil {
@@ -623,16 +1101,50 @@ func (p *Parser) assignExpr() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.AssignExpr(op, left, right), nil
+	return p.locate(p.builder.AssignExpr(op, left, right), left.Loc.Start), nil
 }
 
-// AssignOp
-//   : SIMPLE_ASSIGN
-//   | COMPLEX_ASSIGN
+// ConditionalExpr
+//   : LogicalOrExpr
+//   | LogicalOrExpr '?' AssignExpr ':' AssignExpr
 //   ;
-func (p *Parser) assignOp() (*tokenizer.Token, error) {
-	if p.lookahead.Type == tokenizer.SimpleAssign {
-		return p.consume(tokenizer.SimpleAssign)
+func (p *Parser) conditionalExpr() (ast.Node, error) {
+	cond, err := p.logicalOrExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.lookahead.Type != tokenizer.Question {
+		return cond, nil
+	}
+	if _, err := p.consume(tokenizer.Question); err != nil {
+		return nil, err
+	}
+
+	cons, err := p.assignExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(tokenizer.Colon); err != nil {
+		return nil, err
+	}
+
+	alt, err := p.assignExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ConditionalExpr(cond, cons, alt), cond.Loc.Start), nil
+}
+
+// AssignOp
+//   : SIMPLE_ASSIGN
+//   | COMPLEX_ASSIGN
+//   ;
+func (p *Parser) assignOp() (tokenizer.Token, error) {
+	if p.lookahead.Type == tokenizer.SimpleAssign {
+		return p.consume(tokenizer.SimpleAssign)
 	}
 	return p.consume(tokenizer.ComplexAssign)
 }
@@ -641,34 +1153,37 @@ func (p *Parser) assignOp() (*tokenizer.Token, error) {
 //   : IDENTIFIER
 //   ;
 func (p *Parser) identifier() (ast.Node, error) {
+	start := p.pos()
 	tok, err := p.consume(tokenizer.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.builder.Identifier(tok.Value), nil
+	return p.locate(p.builder.Identifier(tok.Value), start), nil
 }
 
 // ThisExpr
 //   : 'this'
 //   ;
 func (p *Parser) thisExpr() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.ThisKeyword); err != nil {
 		return nil, err
 	}
 
-	return p.builder.ThisExpr(), nil
+	return p.locate(p.builder.ThisExpr(), start), nil
 }
 
 // SuperCall
 //   : 'super'
 //   ;
 func (p *Parser) superCall() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.SuperKeyword); err != nil {
 		return nil, err
 	}
 
-	return p.builder.SuperCall(), nil
+	return p.locate(p.builder.SuperCall(), start), nil
 }
 
 func checkValidAssignTarget(n ast.Node) error {
@@ -677,7 +1192,10 @@ func checkValidAssignTarget(n ast.Node) error {
 		return nil
 	}
 
-	return &ErrInvalidLvalue{Node: n}
+	return &Error{
+		Loc: n.Loc,
+		Err: &ErrInvalidLvalue{Node: n},
+	}
 }
 
 // LogicalOrExpr
@@ -689,11 +1207,35 @@ func (p *Parser) logicalOrExpr() (ast.Node, error) {
 }
 
 // LogicalAndExpr
-//   : EqualExpr LOGICAL_AND LogicalAndExpr
-//   | EqualExpr
+//   : BitOrExpr LOGICAL_AND LogicalAndExpr
+//   | BitOrExpr
 //   ;
 func (p *Parser) logicalAndExpr() (ast.Node, error) {
-	return p.logicalExpr(p.equalExpr, tokenizer.AndLogicalOp)
+	return p.logicalExpr(p.bitOrExpr, tokenizer.AndLogicalOp)
+}
+
+// BitOrExpr
+//   : BitXorExpr
+//   | BitOrExpr BITWISE_OR BitXorExpr
+//   ;
+func (p *Parser) bitOrExpr() (ast.Node, error) {
+	return p.binaryExpr(p.bitXorExpr, tokenizer.BitwiseOrOp)
+}
+
+// BitXorExpr
+//   : BitAndExpr
+//   | BitXorExpr BITWISE_XOR BitAndExpr
+//   ;
+func (p *Parser) bitXorExpr() (ast.Node, error) {
+	return p.binaryExpr(p.bitAndExpr, tokenizer.BitwiseXorOp)
+}
+
+// BitAndExpr
+//   : EqualExpr
+//   | BitAndExpr BITWISE_AND EqualExpr
+//   ;
+func (p *Parser) bitAndExpr() (ast.Node, error) {
+	return p.binaryExpr(p.equalExpr, tokenizer.BitwiseAndOp)
 }
 
 // EqualExpr
@@ -704,11 +1246,19 @@ func (p *Parser) equalExpr() (ast.Node, error) {
 }
 
 // RelExpr
-//   : AddExpr
-//   | RelExpr RELATIONAL_OP AddExpr
+//   : ShiftExpr
+//   | RelExpr RELATIONAL_OP ShiftExpr
 //   ;
 func (p *Parser) relExpr() (ast.Node, error) {
-	return p.binaryExpr(p.addExpr, tokenizer.RelationalOp)
+	return p.binaryExpr(p.shiftExpr, tokenizer.RelationalOp)
+}
+
+// ShiftExpr
+//   : AddExpr
+//   | ShiftExpr SHIFT_OP AddExpr
+//   ;
+func (p *Parser) shiftExpr() (ast.Node, error) {
+	return p.binaryExpr(p.addExpr, tokenizer.ShiftOp)
 }
 
 // AddExpr
@@ -729,6 +1279,7 @@ func (p *Parser) multExpr() (ast.Node, error) {
 
 func (p *Parser) binaryExpr(buildFunc func() (ast.Node, error), tokenType tokenizer.TokenType,
 ) (ast.Node, error) {
+	start := p.pos()
 	left, err := buildFunc()
 	if err != nil {
 		return nil, err
@@ -742,7 +1293,7 @@ func (p *Parser) binaryExpr(buildFunc func() (ast.Node, error), tokenType tokeni
 
 		op := ast.BinaryOpFromString(opToken.Value)
 		if op == ast.InvalidBinaryOp {
-			return nil, &ErrUnknownBinaryOp{Op: opToken.Value}
+			return nil, tokenError(opToken, &ErrUnknownBinaryOp{Op: opToken.Value})
 		}
 
 		right, err := buildFunc()
@@ -750,7 +1301,7 @@ func (p *Parser) binaryExpr(buildFunc func() (ast.Node, error), tokenType tokeni
 			return nil, err
 		}
 
-		left = p.builder.BinaryExpr(op, left, right)
+		left = p.locate(p.builder.BinaryExpr(op, left, right), start)
 	}
 
 	return left, nil
@@ -758,6 +1309,7 @@ func (p *Parser) binaryExpr(buildFunc func() (ast.Node, error), tokenType tokeni
 
 func (p *Parser) logicalExpr(buildFunc func() (ast.Node, error), tokenType tokenizer.TokenType,
 ) (ast.Node, error) {
+	start := p.pos()
 	left, err := buildFunc()
 	if err != nil {
 		return nil, err
@@ -771,7 +1323,7 @@ func (p *Parser) logicalExpr(buildFunc func() (ast.Node, error), tokenType token
 
 		op := ast.LogicalOpFromString(opToken.Value)
 		if op == ast.InvalidLogicalOp {
-			return nil, &ErrUnknownLogicalOp{Op: opToken.Value}
+			return nil, tokenError(opToken, &ErrUnknownLogicalOp{Op: opToken.Value})
 		}
 
 		right, err := buildFunc()
@@ -779,36 +1331,35 @@ func (p *Parser) logicalExpr(buildFunc func() (ast.Node, error), tokenType token
 			return nil, err
 		}
 
-		left = p.builder.LogicalExpr(op, left, right)
+		left = p.locate(p.builder.LogicalExpr(op, left, right), start)
 	}
 
 	return left, nil
 }
 
 // UnaryExpr
-//   : LeftHandSideExpr
+//   : ExpExpr
 //   | ADDITIVE_OP UnaryExpr
 //   | LOGICAL_NOT UnaryExpr
+//   | BITWISE_NOT UnaryExpr
+//   | 'typeof' UnaryExpr
 //   ;
 func (p *Parser) unaryExpr() (ast.Node, error) {
-	var opTok *tokenizer.Token
+	start := p.pos()
+	var opTok tokenizer.Token
 	var err error
 	switch p.lookahead.Type {
-	case tokenizer.AdditiveOp:
-		if opTok, err = p.consume(tokenizer.AdditiveOp); err != nil {
-			return nil, err
-		}
-	case tokenizer.NotLogicalOp:
-		if opTok, err = p.consume(tokenizer.NotLogicalOp); err != nil {
+	case tokenizer.AdditiveOp, tokenizer.NotLogicalOp, tokenizer.BitwiseNotOp, tokenizer.TypeofKeyword:
+		if opTok, err = p.consume(p.lookahead.Type); err != nil {
 			return nil, err
 		}
 	default:
-		return p.leftHandSideExpr()
+		return p.expExpr()
 	}
 
 	op := ast.UnaryOpFromString(opTok.Value)
 	if op == ast.InvalidUnaryOp {
-		return nil, &ErrUnknownUnaryOp{Op: opTok.Value}
+		return nil, tokenError(opTok, &ErrUnknownUnaryOp{Op: opTok.Value})
 	}
 
 	arg, err := p.unaryExpr()
@@ -816,7 +1367,74 @@ func (p *Parser) unaryExpr() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.UnaryExpr(op, arg), nil
+	return p.locate(p.builder.UnaryExpr(op, arg), start), nil
+}
+
+// ExpExpr
+//   : UpdateExpr
+//   | UpdateExpr EXPONENT_OP UnaryExpr
+//   ;
+//
+// The exponentiation is right-associative and binds tighter than a unary
+// operator on its left, so that -a ** b is -(a ** b).
+func (p *Parser) expExpr() (ast.Node, error) {
+	start := p.pos()
+	left, err := p.updateExpr()
+	if err != nil || p.lookahead.Type != tokenizer.ExponentOp {
+		return left, err
+	}
+
+	opToken, err := p.consume(tokenizer.ExponentOp)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.BinaryExpr(ast.BinaryOpFromString(opToken.Value), left, right), start), nil
+}
+
+// UpdateExpr
+//   : LeftHandSideExpr
+//   | LeftHandSideExpr UPDATE_OP
+//   | UPDATE_OP LeftHandSideExpr
+//   ;
+func (p *Parser) updateExpr() (ast.Node, error) {
+	start := p.pos()
+	if p.lookahead.Type == tokenizer.UpdateOp {
+		opTok, err := p.consume(tokenizer.UpdateOp)
+		if err != nil {
+			return nil, err
+		}
+
+		arg, err := p.leftHandSideExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkValidAssignTarget(arg); err != nil {
+			return nil, err
+		}
+
+		return p.locate(p.builder.UpdateExpr(ast.UpdateOpFromString(opTok.Value), true, arg), start), nil
+	}
+
+	arg, err := p.leftHandSideExpr()
+	if err != nil || p.lookahead.Type != tokenizer.UpdateOp {
+		return arg, err
+	}
+	if err := checkValidAssignTarget(arg); err != nil {
+		return nil, err
+	}
+
+	opTok, err := p.consume(tokenizer.UpdateOp)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.UpdateExpr(ast.UpdateOpFromString(opTok.Value), false, arg), start), nil
 }
 
 // LeftHandSideExpr
@@ -866,7 +1484,7 @@ func (p *Parser) callExpr(callee ast.Node) (ast.Node, error) {
 		return nil, err
 	}
 
-	callExpr := p.builder.CallExpr(callee, args)
+	callExpr := p.locate(p.builder.CallExpr(callee, args), callee.Loc.Start)
 
 	if p.lookahead.Type == tokenizer.OpenParens {
 		callExpr, err = p.callExpr(callExpr)
@@ -882,6 +1500,7 @@ func (p *Parser) callExpr(callee ast.Node) (ast.Node, error) {
 //   : 'new' MemberExpression CallArgs
 //   ;
 func (p *Parser) newExpr() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.NewKeyword); err != nil {
 		return nil, err
 	}
@@ -896,7 +1515,7 @@ func (p *Parser) newExpr() (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.NewExpr(member, args), nil
+	return p.locate(p.builder.NewExpr(member, args), start), nil
 }
 
 // CallArgs
@@ -954,6 +1573,7 @@ func (p *Parser) argList() ([]ast.Node, error) {
 //   | MemberExpr '[' SeqExpr ']'
 //   ;
 func (p *Parser) memberExpr() (ast.Node, error) {
+	start := p.pos()
 	obj, err := p.primaryExpr()
 	if err != nil {
 		return nil, err
@@ -968,7 +1588,7 @@ func (p *Parser) memberExpr() (ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			obj = p.builder.MemberExpr(false, obj, prop)
+			obj = p.locate(p.builder.MemberExpr(false, obj, prop), start)
 		} else if p.lookahead.Type == tokenizer.OpenSquare {
 			if _, err := p.consume(tokenizer.OpenSquare); err != nil {
 				return nil, err
@@ -980,7 +1600,7 @@ func (p *Parser) memberExpr() (ast.Node, error) {
 			if _, err := p.consume(tokenizer.CloseSquare); err != nil {
 				return nil, err
 			}
-			obj = p.builder.MemberExpr(true, obj, prop)
+			obj = p.locate(p.builder.MemberExpr(true, obj, prop), start)
 		} else {
 			break
 		}
@@ -995,7 +1615,15 @@ func (p *Parser) memberExpr() (ast.Node, error) {
 //   | Identifier
 //   | ThisExpr
 //   | NewExpr
+//   | ArrayLit
+//   | ObjectLit
+//   | FuncExpr
+//   | ArrowFunc
 //   ;
+//
+// The '{' at the beginning of a statement starts a BlockStmt and 'def'
+// starts a FuncDecl, the ObjectLit and the FuncExpr have to be
+// parenthesized there.
 func (p *Parser) primaryExpr() (ast.Node, error) {
 	if isLiteral(p.lookahead.Type) {
 		return p.literal()
@@ -1004,13 +1632,26 @@ func (p *Parser) primaryExpr() (ast.Node, error) {
 	case tokenizer.OpenParens:
 		return p.parensExpr()
 	case tokenizer.Identifier:
-		return p.identifier()
+		start := p.pos()
+		id, err := p.identifier()
+		if err != nil || p.lookahead.Type != tokenizer.Arrow {
+			return id, err
+		}
+		return p.arrowFunc([]ast.Node{id}, start)
+	case tokenizer.DefKeyword:
+		return p.funcExpr()
 	case tokenizer.ThisKeyword:
 		return p.thisExpr()
 	case tokenizer.NewKeyword:
 		return p.newExpr()
-	default:
+	case tokenizer.OpenSquare:
+		return p.arrayLit()
+	case tokenizer.OpenCurlyBrace:
+		return p.objectLit()
+	case tokenizer.SuperKeyword:
 		return p.leftHandSideExpr()
+	default:
+		return nil, p.unexpected("PrimaryExpr")
 	}
 }
 
@@ -1027,14 +1668,203 @@ func isLiteral(t tokenizer.TokenType) bool {
 	}
 }
 
+// ArrayLit
+//   : '[' ElementList ']'
+//   ;
+//
+// ElementList
+//   : OptAssignExpr
+//   | ElementList ',' OptAssignExpr
+//   ;
+//
+// The element left out is a hole except for the last one, so that [a, b,]
+// has two elements and [a, , b] has three.
+func (p *Parser) arrayLit() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.OpenSquare); err != nil {
+		return nil, err
+	}
+
+	var elements []ast.Node
+	for p.lookahead.Type != tokenizer.CloseSquare {
+		if p.lookahead.Type == tokenizer.Comma {
+			if _, err := p.consume(tokenizer.Comma); err != nil {
+				return nil, err
+			}
+			elements = append(elements, nil)
+			continue
+		}
+
+		el, err := p.assignExpr()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, el)
+
+		if p.lookahead.Type == tokenizer.CloseSquare {
+			break
+		}
+		if _, err := p.consume(tokenizer.Comma); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(tokenizer.CloseSquare); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ArrayLit(elements...), start), nil
+}
+
+// ObjectLit
+//   : '{' '}'
+//   | '{' PropertyList '}'
+//   | '{' PropertyList ',' '}'
+//   ;
+//
+// PropertyList
+//   : Property
+//   | PropertyList ',' Property
+//   ;
+func (p *Parser) objectLit() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.OpenCurlyBrace); err != nil {
+		return nil, err
+	}
+
+	var props []ast.Node
+	for p.lookahead.Type != tokenizer.CloseCurlyBrace {
+		prop, err := p.property()
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+
+		if p.lookahead.Type == tokenizer.CloseCurlyBrace {
+			break
+		}
+		if _, err := p.consume(tokenizer.Comma); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(tokenizer.CloseCurlyBrace); err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ObjectLit(props...), start), nil
+}
+
+// Property
+//   : PropertyKey ':' AssignExpr
+//   | '[' AssignExpr ']' ':' AssignExpr
+//   | Identifier
+//   | Identifier '(' OptFormalParamList ')' BlockStmt
+//   ;
+//
+// PropertyKey
+//   : Identifier
+//   | StringLit
+//   | NumericLit
+//   ;
+func (p *Parser) property() (ast.Node, error) {
+	start := p.pos()
+
+	var key ast.Node
+	computed := false
+	switch p.lookahead.Type {
+	case tokenizer.Identifier:
+		var err error
+		if key, err = p.identifier(); err != nil {
+			return nil, err
+		}
+
+		switch p.lookahead.Type {
+		case tokenizer.Comma, tokenizer.CloseCurlyBrace:
+			value := p.builder.Identifier(key.Fields.(*ast.Identifier).Name)
+			value.Loc = key.Loc
+			return p.locate(p.builder.Property(ast.ShorthandProperty, false, key, value), start), nil
+		case tokenizer.OpenParens:
+			value, err := p.method(key)
+			if err != nil {
+				return nil, err
+			}
+			return p.locate(p.builder.Property(ast.MethodProperty, false, key, value), start), nil
+		}
+	case tokenizer.String:
+		var err error
+		if key, err = p.stringLit(); err != nil {
+			return nil, err
+		}
+	case tokenizer.Number:
+		var err error
+		if key, err = p.numericLit(); err != nil {
+			return nil, err
+		}
+	case tokenizer.OpenSquare:
+		if _, err := p.consume(tokenizer.OpenSquare); err != nil {
+			return nil, err
+		}
+		var err error
+		if key, err = p.assignExpr(); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(tokenizer.CloseSquare); err != nil {
+			return nil, err
+		}
+		computed = true
+	default:
+		return nil, p.unexpected("Property")
+	}
+
+	if _, err := p.consume(tokenizer.Colon); err != nil {
+		return nil, err
+	}
+
+	value, err := p.assignExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.Property(ast.InitProperty, computed, key, value), start), nil
+}
+
+// method parses the parameters and the body of the method shorthand, the
+// method is the FuncDecl named as the key.
+func (p *Parser) method(key ast.Node) (ast.Node, error) {
+	params, err := p.formalParams()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.funcBody()
+	if err != nil {
+		return nil, err
+	}
+
+	name := p.builder.Identifier(key.Fields.(*ast.Identifier).Name)
+	name.Loc = key.Loc
+	return p.locate(p.builder.FuncDecl(name, params, body), key.Loc.Start), nil
+}
+
 // ParensExpr
 //   : '(' SeqExpr ')'
 //   ;
+//
+// The ParensExpr followed by '=>' is the parameter list of the ArrowFunc.
 func (p *Parser) parensExpr() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.OpenParens); err != nil {
 		return nil, err
 	}
 
+	if p.lookahead.Type == tokenizer.CloseParens {
+		if _, err := p.consume(tokenizer.CloseParens); err != nil {
+			return nil, err
+		}
+		return p.arrowFunc(nil, start)
+	}
+
 	expr, err := p.seqExpr()
 	if err != nil {
 		return nil, err
@@ -1044,9 +1874,98 @@ func (p *Parser) parensExpr() (ast.Node, error) {
 		return nil, err
 	}
 
+	if p.lookahead.Type == tokenizer.Arrow {
+		params, err := arrowParams(expr)
+		if err != nil {
+			return nil, err
+		}
+		return p.arrowFunc(params, start)
+	}
+
 	return expr, nil
 }
 
+// arrowParams converts the expression in parentheses to the parameters of
+// the ArrowFunc, it is either an Identifier or a SeqExpr of them.
+func arrowParams(expr ast.Node) ([]ast.Node, error) {
+	params := []ast.Node{expr}
+	if seq, ok := expr.Fields.(*ast.SeqExpr); ok {
+		params = seq.Body
+	}
+
+	for _, param := range params {
+		if param.Type != ast.IdentifierType {
+			return nil, &Error{
+				Loc: param.Loc,
+				Err: &ErrInvalidParam{Node: param},
+			}
+		}
+	}
+	return params, nil
+}
+
+// ArrowFunc
+//   : Identifier '=>' ArrowBody
+//   | '(' OptFormalParamList ')' '=>' ArrowBody
+//   ;
+//
+// ArrowBody
+//   : BlockStmt
+//   | AssignExpr
+//   ;
+//
+// The '{' starts the BlockStmt, the ObjectLit returned by the function has
+// to be parenthesized.
+func (p *Parser) arrowFunc(params []ast.Node, start ast.Position) (ast.Node, error) {
+	if _, err := p.consume(tokenizer.Arrow); err != nil {
+		return nil, err
+	}
+
+	var body ast.Node
+	var err error
+	if p.lookahead.Type == tokenizer.OpenCurlyBrace {
+		body, err = p.funcBody()
+	} else {
+		body, err = p.assignExpr()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.ArrowFunc(params, body), start), nil
+}
+
+// FuncExpr
+//   : 'def' FormalParams BlockStmt
+//   | 'def' Identifier FormalParams BlockStmt
+//   ;
+func (p *Parser) funcExpr() (ast.Node, error) {
+	start := p.pos()
+	if _, err := p.consume(tokenizer.DefKeyword); err != nil {
+		return nil, err
+	}
+
+	var name ast.Node
+	if p.lookahead.Type == tokenizer.Identifier {
+		var err error
+		if name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+	}
+
+	params, err := p.formalParams()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.funcBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.locate(p.builder.FuncExpr(name, params, body), start), nil
+}
+
 // Literal
 //   : NumericLit
 //   | StringLit
@@ -1066,10 +1985,10 @@ func (p *Parser) literal() (ast.Node, error) {
 	case tokenizer.NullKeyword:
 		return p.nullLit()
 	default:
-		return nil, &ErrUnknownLiteral{
+		return nil, tokenError(p.lookahead, &ErrUnknownLiteral{
 			Type:  p.lookahead.Type,
 			Value: p.lookahead.Value,
-		}
+		})
 	}
 }
 
@@ -1077,29 +1996,201 @@ func (p *Parser) literal() (ast.Node, error) {
 //   : NUMBER
 //   ;
 func (p *Parser) numericLit() (ast.Node, error) {
+	start := p.pos()
 	token, err := p.consume(tokenizer.Number)
 	if err != nil {
 		return nil, err
 	}
 
-	n, err := strconv.ParseInt(token.Value, 10, 64)
+	n, err := p.parseNumber(token.Value)
 	if err != nil {
-		return nil, err
+		return nil, tokenError(token, err)
+	}
+
+	return p.locate(n, start), nil
+}
+
+// parseNumber converts the raw numeric literal, the literals with a
+// fraction or an exponent are floating point, the rest are integers.
+func (p *Parser) parseNumber(raw string) (ast.Node, error) {
+	digits := strings.ReplaceAll(raw, "_", "")
+
+	base := 10
+	if len(digits) > 1 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+
+	if base == 10 && strings.ContainsAny(digits, ".eE") {
+		f, err := strconv.ParseFloat(digits, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, &ErrNumericOverflow{Raw: raw, Kind: ast.FloatNumeric}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return p.builder.FloatLit(raw, f), nil
 	}
 
-	return p.builder.NumericLit(int(n)), nil
+	if base != 10 {
+		digits = digits[2:]
+	}
+	n, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, &ErrNumericOverflow{Raw: raw, Kind: ast.IntNumeric}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.builder.IntLit(raw, n), nil
 }
 
 // StringLit
 //   : STRING
 //   ;
 func (p *Parser) stringLit() (ast.Node, error) {
+	start := p.pos()
 	token, err := p.consume(tokenizer.String)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.builder.StringLit(token.Value[1 : len(token.Value)-1]), nil
+	s, err := unquote(token.Value)
+	if err != nil {
+		var escErr *ErrInvalidEscape
+		if errors.As(err, &escErr) {
+			// Point at the escape sequence itself, the literal is on a single line
+			loc := tokenError(token, err).Loc
+			loc.Start.Offset += escErr.Offset
+			loc.Start.Column += escErr.Offset
+			loc.End = loc.Start
+			loc.End.Offset += len(escErr.Escape)
+			loc.End.Column += len(escErr.Escape)
+			return nil, &Error{Loc: loc, Err: err}
+		}
+		return nil, tokenError(token, err)
+	}
+
+	return p.locate(p.builder.StringLitRaw(token.Value, s), start), nil
+}
+
+// unquote decodes the quoted string literal. The escape sequences are
+// \n, \t, \r, \b, \f, \v, \0, \\, \", \', \uXXXX and \u{X...}, the
+// surrogate pairs of \uXXXX escapes are combined.
+func unquote(raw string) (string, error) {
+	s := raw[1 : len(raw)-1]
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		r, n, ok := unescape(s[i:])
+		if !ok {
+			return "", &ErrInvalidEscape{Escape: s[i : i+n], Offset: i + 1}
+		}
+		sb.WriteRune(r)
+		i += n
+	}
+
+	return sb.String(), nil
+}
+
+// unescape decodes the escape sequence at the beginning of s. It returns
+// the rune and the length of the sequence, or the length of the invalid
+// part of it.
+func unescape(s string) (rune, int, bool) {
+	if len(s) < 2 {
+		return 0, len(s), false
+	}
+
+	switch s[1] {
+	case 'n':
+		return '\n', 2, true
+	case 't':
+		return '\t', 2, true
+	case 'r':
+		return '\r', 2, true
+	case 'b':
+		return '\b', 2, true
+	case 'f':
+		return '\f', 2, true
+	case 'v':
+		return '\v', 2, true
+	case '0':
+		return 0, 2, true
+	case '\\', '"', '\'':
+		return rune(s[1]), 2, true
+	case 'u':
+		// Handled below
+	default:
+		_, size := utf8.DecodeRuneInString(s[1:])
+		return 0, 1 + size, false
+	}
+
+	if strings.HasPrefix(s, `\u{`) {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, 3, false
+		}
+		r, err := strconv.ParseUint(s[3:end], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return 0, end + 1, false
+		}
+		return rune(r), end + 1, true
+	}
+
+	r, n := hex4(s[2:])
+	if n < 4 {
+		return 0, 2 + n, false
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, 6, true
+	}
+
+	// A high surrogate must be followed by the low one
+	if !strings.HasPrefix(s[6:], `\u`) {
+		return 0, 6, false
+	}
+	low, n := hex4(s[8:])
+	if r = utf16.DecodeRune(r, low); n < 4 || r == utf8.RuneError {
+		return 0, 6, false
+	}
+	return r, 12, true
+}
+
+// hex4 decodes up to four hex digits at the beginning of s, it returns the
+// number of digits decoded.
+func hex4(s string) (rune, int) {
+	var r rune
+	n := 0
+	for ; n < 4 && n < len(s); n++ {
+		var d byte
+		switch c := s[n]; {
+		case '0' <= c && c <= '9':
+			d = c - '0'
+		case 'a' <= c && c <= 'f':
+			d = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			d = c - 'A' + 10
+		default:
+			return r, n
+		}
+		r = r<<4 | rune(d)
+	}
+	return r, n
 }
 
 // BoolLit
@@ -1107,6 +2198,7 @@ func (p *Parser) stringLit() (ast.Node, error) {
 //   | 'false'
 //   ;
 func (p *Parser) boolLit(v bool) (ast.Node, error) {
+	start := p.pos()
 	tokType := tokenizer.FalseKeyword
 	if v {
 		tokType = tokenizer.TrueKeyword
@@ -1116,40 +2208,162 @@ func (p *Parser) boolLit(v bool) (ast.Node, error) {
 		return nil, err
 	}
 
-	return p.builder.BoolLit(v), nil
+	return p.locate(p.builder.BoolLit(v), start), nil
 }
 
 // NullLit
 //   : 'null'
 //   ;
 func (p *Parser) nullLit() (ast.Node, error) {
+	start := p.pos()
 	if _, err := p.consume(tokenizer.NullKeyword); err != nil {
 		return nil, err
 	}
 
-	return p.builder.NullLit(), nil
+	return p.locate(p.builder.NullLit(), start), nil
 }
 
-func (p *Parser) consume(tokType tokenizer.TokenType) (*tokenizer.Token, error) {
+func (p *Parser) consume(tokType tokenizer.TokenType) (tokenizer.Token, error) {
 	token := p.lookahead
 
-	if token == nil || token.Type == tokenizer.EOF {
-		return nil, &ErrUnexpectedEndOfInput{Type: tokType}
+	if token.Type != tokType {
+		return tokenizer.Token{}, p.unexpected(tokType)
 	}
 
-	if token.Type != tokType {
-		return nil, &ErrUnexpectedToken{
-			Type:         token.Type,
-			Value:        token.Value,
-			ExpectedType: tokType,
+	if err := p.next(); err != nil {
+		return tokenizer.Token{}, err
+	}
+
+	return token, nil
+}
+
+// next advances the lookahead to the next token. While recovering, tokenizer
+// errors are recorded and the offending input is skipped.
+func (p *Parser) next() error {
+	p.prevEnd = p.lookahead.End
+	p.leadComment = nil
+
+	var group *ast.CommentGroup
+	for {
+		tok, err := p.tokenizer.NextToken()
+		if err == nil && tok.Type == tokenizer.Comment {
+			if p.keepComments {
+				group = p.addComment(group, tok)
+			}
+			continue
 		}
+		if err == nil {
+			if group != nil && lastLine(group) >= tok.Start.Line-1 {
+				p.leadComment = group
+			}
+			p.lookahead = tok
+			return nil
+		}
+
+		switch tokErr := err.(type) {
+		case *tokenizer.ErrUnexpectedToken:
+			err = codeError(tokErr.Position, tokErr.CodeString, err)
+		case *tokenizer.ErrUnterminatedString:
+			err = codeError(tokErr.Position, tokErr.CodeString, err)
+		}
+		if !p.recovering {
+			return err
+		}
+		p.addError(err)
 	}
+}
 
-	var err error
-	p.lookahead, err = p.tokenizer.NextToken()
-	if err != nil {
-		return nil, err
+// addComment records the comment and returns the group it belongs to. The
+// comments on the line of the previous token are not grouped, they can't
+// be doc comments.
+func (p *Parser) addComment(group *ast.CommentGroup, tok tokenizer.Token) *ast.CommentGroup {
+	c := &ast.Comment{
+		Text: tok.Value,
+		Loc:  ast.Loc{Start: ast.Position(tok.Start), End: ast.Position(tok.End)},
 	}
+	p.comments = append(p.comments, c)
 
-	return token, nil
+	switch {
+	case c.Loc.Start.Line == p.prevEnd.Line:
+		return nil
+	case group != nil && c.Loc.Start.Line <= lastLine(group)+1:
+		group.List = append(group.List, c)
+		return group
+	default:
+		return &ast.CommentGroup{List: []*ast.Comment{c}}
+	}
+}
+
+func lastLine(group *ast.CommentGroup) int {
+	return group.List[len(group.List)-1].Loc.End.Line
+}
+
+// unexpected returns an error for the lookahead token which doesn't match
+// the expected type.
+func (p *Parser) unexpected(expected tokenizer.TokenType) error {
+	if p.lookahead.Type == tokenizer.EOF {
+		return tokenError(p.lookahead, &ErrUnexpectedEndOfInput{Type: expected})
+	}
+
+	return tokenError(p.lookahead, &ErrUnexpectedToken{
+		Type:         p.lookahead.Type,
+		Value:        p.lookahead.Value,
+		ExpectedType: expected,
+	})
+}
+
+func (p *Parser) addError(err error) {
+	e, ok := err.(*Error)
+	if !ok {
+		e = tokenError(p.lookahead, err)
+	}
+	p.errors = append(p.errors, e)
+}
+
+// lookaheadIn reports whether the lookahead token is of one of the types.
+func (p *Parser) lookaheadIn(types []tokenizer.TokenType) bool {
+	for _, t := range types {
+		if p.lookahead.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenError(tok tokenizer.Token, err error) *Error {
+	return &Error{
+		Loc: ast.Loc{
+			Start: ast.Position(tok.Start),
+			End:   ast.Position(tok.End),
+		},
+		Err: err,
+	}
+}
+
+// codeError ties the tokenizer error to the span of the code starting at
+// the position.
+func codeError(pos tokenizer.Position, code string, err error) *Error {
+	start := ast.Position(pos)
+	end := start
+	end.Offset += len(code)
+	end.Column += len(code)
+	return &Error{
+		Loc: ast.Loc{Start: start, End: end},
+		Err: err,
+	}
+}
+
+// pos returns the start position of the lookahead token.
+func (p *Parser) pos() ast.Position {
+	return ast.Position(p.lookahead.Start)
+}
+
+// locate sets the location of the node to span from start to the end of
+// the last consumed token.
+func (p *Parser) locate(n ast.Node, start ast.Position) ast.Node {
+	n.Loc = ast.Loc{
+		Start: start,
+		End:   ast.Position(p.prevEnd),
+	}
+	return n
 }
@@ -3,9 +3,14 @@ package parser
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
 	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
@@ -30,6 +35,49 @@ func TestParser_Parse_Literal(t *testing.T) {
 			wantAST: b.Program(
 				b.ExprStmt(b.StringLit(`hello`)),
 			),
+		}, {
+			in: `"a\tb\n", 'it\'s', "say \"hi\"", "\\", "\u00e9\u{1F600}\uD83D\uDE00";`,
+			wantAST: b.Program(
+				b.ExprStmt(b.SeqExpr(
+					b.StringLit("a\tb\n"),
+					b.StringLitRaw(`'it\'s'`, "it's"),
+					b.StringLit(`say "hi"`),
+					b.StringLit(`\`),
+					b.StringLitRaw(`"\u00e9\u{1F600}\uD83D\uDE00"`, "é😀😀"),
+				)),
+			),
+		}, {
+			in: `0xFF, 0o17, 0B101, 1_000_000, 9223372036854775807;`,
+			wantAST: b.Program(
+				b.ExprStmt(b.SeqExpr(
+					b.IntLit("0xFF", 255),
+					b.IntLit("0o17", 15),
+					b.IntLit("0B101", 5),
+					b.IntLit("1_000_000", 1000000),
+					b.IntLit("9223372036854775807", 9223372036854775807),
+				)),
+			),
+		}, {
+			in: `3.14, .5, 1e9, 2.5E-3, 1_0.0_1;`,
+			wantAST: b.Program(
+				b.ExprStmt(b.SeqExpr(
+					b.FloatLit("3.14", 3.14),
+					b.FloatLit(".5", 0.5),
+					b.FloatLit("1e9", 1e9),
+					b.FloatLit("2.5E-3", 2.5e-3),
+					b.FloatLit("1_0.0_1", 10.01),
+				)),
+			),
+		}, {
+			in: `a.b.c[1.5];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.MemberExpr(true,
+					b.MemberExpr(false,
+						b.MemberExpr(false, b.Identifier("a"), b.Identifier("b")),
+						b.Identifier("c")),
+					b.FloatLit("1.5", 1.5),
+				)),
+			),
 		},
 	}
 
@@ -289,6 +337,65 @@ func TestParser_Parse_Math(t *testing.T) {
 					),
 				),
 			),
+		}, {
+			in: `a % b ** c ** d;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.ModBinaryOp,
+						b.Identifier("a"),
+						b.BinaryExpr(
+							ast.ExpBinaryOp,
+							b.Identifier("b"),
+							b.BinaryExpr(
+								ast.ExpBinaryOp,
+								b.Identifier("c"),
+								b.Identifier("d"),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `-a ** -b;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.UnaryExpr(
+						ast.NegUnaryOp,
+						b.BinaryExpr(
+							ast.ExpBinaryOp,
+							b.Identifier("a"),
+							b.UnaryExpr(
+								ast.NegUnaryOp,
+								b.Identifier("b"),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `a << b + c >>> d < e;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.LtBinaryOp,
+						b.BinaryExpr(
+							ast.UShrBinaryOp,
+							b.BinaryExpr(
+								ast.ShlBinaryOp,
+								b.Identifier("a"),
+								b.BinaryExpr(
+									ast.AddBinaryOp,
+									b.Identifier("b"),
+									b.Identifier("c"),
+								),
+							),
+							b.Identifier("d"),
+						),
+						b.Identifier("e"),
+					),
+				),
+			),
 		},
 	}
 
@@ -342,6 +449,21 @@ func TestParser_Parse_Assign(t *testing.T) {
 					),
 				),
 			),
+		}, {
+			in: `x **= y >>>= 2;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.AssignExpr(
+						ast.ExpAssignOp,
+						b.Identifier("x"),
+						b.AssignExpr(
+							ast.UShrAssignOp,
+							b.Identifier("y"),
+							b.NumericLit(2),
+						),
+					),
+				),
+			),
 		},
 	}
 
@@ -368,6 +490,21 @@ func TestParser_Parse_Variable(t *testing.T) {
 					),
 				),
 			),
+		}, {
+			in: `let délai = $el, _名前;`,
+			wantAST: b.Program(
+				b.VarStmt(
+					b.VarDecl(b.Identifier("délai"), b.Identifier("$el")),
+					b.VarDecl(b.Identifier("_名前"), nil),
+				),
+			),
+		}, {
+			in: `let letter = iffy;`,
+			wantAST: b.Program(
+				b.VarStmt(
+					b.VarDecl(b.Identifier("letter"), b.Identifier("iffy")),
+				),
+			),
 		}, {
 			in: `let x;`,
 			wantAST: b.Program(
@@ -451,6 +588,25 @@ func TestParser_Parse_Relational(t *testing.T) {
 	}
 	tests := []test{
 		{
+			in: `a instanceof B < ("k" in o);`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.LtBinaryOp,
+						b.BinaryExpr(
+							ast.InstanceofBinaryOp,
+							b.Identifier("a"),
+							b.Identifier("B"),
+						),
+						b.BinaryExpr(
+							ast.InBinaryOp,
+							b.StringLit("k"),
+							b.Identifier("o"),
+						),
+					),
+				),
+			),
+		}, {
 			in: `x > 0;`,
 			wantAST: b.Program(
 				b.ExprStmt(
@@ -526,6 +682,25 @@ func TestParser_Parse_Equality(t *testing.T) {
 	}
 	tests := []test{
 		{
+			in: `a === b !== c in d;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.StrictNeqBinaryOp,
+						b.BinaryExpr(
+							ast.StrictEqBinaryOp,
+							b.Identifier("a"),
+							b.Identifier("b"),
+						),
+						b.BinaryExpr(
+							ast.InBinaryOp,
+							b.Identifier("c"),
+							b.Identifier("d"),
+						),
+					),
+				),
+			),
+		}, {
 			in: `x == 0;`,
 			wantAST: b.Program(
 				b.ExprStmt(
@@ -688,6 +863,152 @@ func TestParser_Parse_Logical(t *testing.T) {
 					),
 				),
 			),
+		}, {
+			in: `x || y ? a : b && c;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.ConditionalExpr(
+						b.LogicalExpr(
+							ast.OrLogicalOp,
+							b.Identifier("x"),
+							b.Identifier("y"),
+						),
+						b.Identifier("a"),
+						b.LogicalExpr(
+							ast.AndLogicalOp,
+							b.Identifier("b"),
+							b.Identifier("c"),
+						),
+					),
+				),
+			),
+		}, {
+			in: `a ? b : c ? d : e;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.ConditionalExpr(
+						b.Identifier("a"),
+						b.Identifier("b"),
+						b.ConditionalExpr(
+							b.Identifier("c"),
+							b.Identifier("d"),
+							b.Identifier("e"),
+						),
+					),
+				),
+			),
+		}, {
+			in: `a ? b ? c : d : e;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.ConditionalExpr(
+						b.Identifier("a"),
+						b.ConditionalExpr(
+							b.Identifier("b"),
+							b.Identifier("c"),
+							b.Identifier("d"),
+						),
+						b.Identifier("e"),
+					),
+				),
+			),
+		}, {
+			in: `x = a ? y = 1 : z = 2;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.AssignExpr(
+						ast.SimpleAssignOp,
+						b.Identifier("x"),
+						b.ConditionalExpr(
+							b.Identifier("a"),
+							b.AssignExpr(
+								ast.SimpleAssignOp,
+								b.Identifier("y"),
+								b.NumericLit(1),
+							),
+							b.AssignExpr(
+								ast.SimpleAssignOp,
+								b.Identifier("z"),
+								b.NumericLit(2),
+							),
+						),
+					),
+				),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_Bitwise(t *testing.T) {
+	type test struct {
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `a | b ^ c & d == e;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.BitOrBinaryOp,
+						b.Identifier("a"),
+						b.BinaryExpr(
+							ast.BitXorBinaryOp,
+							b.Identifier("b"),
+							b.BinaryExpr(
+								ast.BitAndBinaryOp,
+								b.Identifier("c"),
+								b.BinaryExpr(
+									ast.EqBinaryOp,
+									b.Identifier("d"),
+									b.Identifier("e"),
+								),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `a && b | c;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.LogicalExpr(
+						ast.AndLogicalOp,
+						b.Identifier("a"),
+						b.BinaryExpr(
+							ast.BitOrBinaryOp,
+							b.Identifier("b"),
+							b.Identifier("c"),
+						),
+					),
+				),
+			),
+		}, {
+			in: `~a & ~~b;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.BitAndBinaryOp,
+						b.UnaryExpr(
+							ast.BitNotUnaryOp,
+							b.Identifier("a"),
+						),
+						b.UnaryExpr(
+							ast.BitNotUnaryOp,
+							b.UnaryExpr(
+								ast.BitNotUnaryOp,
+								b.Identifier("b"),
+							),
+						),
+					),
+				),
+			),
 		},
 	}
 
@@ -818,6 +1139,23 @@ func TestParser_Parse_Unary(t *testing.T) {
 	}
 	tests := []test{
 		{
+			in: `typeof -x == "number";`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.BinaryExpr(
+						ast.EqBinaryOp,
+						b.UnaryExpr(
+							ast.TypeofUnaryOp,
+							b.UnaryExpr(
+								ast.NegUnaryOp,
+								b.Identifier("x"),
+							),
+						),
+						b.StringLit("number"),
+					),
+				),
+			),
+		}, {
 			in: `-x;`,
 			wantAST: b.Program(
 				b.ExprStmt(
@@ -861,56 +1199,131 @@ func TestParser_Parse_Unary(t *testing.T) {
 	}
 }
 
-func TestParser_Parse_Loops(t *testing.T) {
+func TestParser_Parse_Update(t *testing.T) {
 	type test struct {
-		name    string
 		in      string
 		wantAST ast.Node
 	}
 	tests := []test{
 		{
-			in: `while (x > 10) { x -= 1;}`,
+			in: `++x;`,
 			wantAST: b.Program(
-				b.WhileStmt(
-					b.BinaryExpr(
-						ast.GtBinaryOp,
+				b.ExprStmt(
+					b.UpdateExpr(
+						ast.IncUpdateOp,
+						true,
 						b.Identifier("x"),
-						b.NumericLit(10),
 					),
-					b.BlockStmt(
-						b.ExprStmt(
-							b.AssignExpr(
-								ast.SubAssignOp,
-								b.Identifier("x"),
-								b.NumericLit(1),
-							),
+				),
+			),
+		}, {
+			in: `a.b--;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.UpdateExpr(
+						ast.DecUpdateOp,
+						false,
+						b.MemberExpr(
+							false,
+							b.Identifier("a"),
+							b.Identifier("b"),
 						),
 					),
 				),
 			),
 		}, {
-			in: `do { x -= 1; } while (x > 10);`,
+			in: `a+++b;`,
 			wantAST: b.Program(
-				b.DoWhileStmt(
+				b.ExprStmt(
 					b.BinaryExpr(
-						ast.GtBinaryOp,
-						b.Identifier("x"),
-						b.NumericLit(10),
+						ast.AddBinaryOp,
+						b.UpdateExpr(
+							ast.IncUpdateOp,
+							false,
+							b.Identifier("a"),
+						),
+						b.Identifier("b"),
 					),
-					b.BlockStmt(
-						b.ExprStmt(
-							b.AssignExpr(
-								ast.SubAssignOp,
+				),
+			),
+		}, {
+			in: `- --x ** 2;`,
+			wantAST: b.Program(
+				b.ExprStmt(
+					b.UnaryExpr(
+						ast.NegUnaryOp,
+						b.BinaryExpr(
+							ast.ExpBinaryOp,
+							b.UpdateExpr(
+								ast.DecUpdateOp,
+								true,
 								b.Identifier("x"),
-								b.NumericLit(1),
 							),
+							b.NumericLit(2),
 						),
 					),
 				),
 			),
-		}, {
-			in: `for (let i = 0; i < 10; i += 10) {	x += 1; }`,
-			wantAST: b.Program(
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_Loops(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `while (x > 10) { x -= 1;}`,
+			wantAST: b.Program(
+				b.WhileStmt(
+					b.BinaryExpr(
+						ast.GtBinaryOp,
+						b.Identifier("x"),
+						b.NumericLit(10),
+					),
+					b.BlockStmt(
+						b.ExprStmt(
+							b.AssignExpr(
+								ast.SubAssignOp,
+								b.Identifier("x"),
+								b.NumericLit(1),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `do { x -= 1; } while (x > 10);`,
+			wantAST: b.Program(
+				b.DoWhileStmt(
+					b.BinaryExpr(
+						ast.GtBinaryOp,
+						b.Identifier("x"),
+						b.NumericLit(10),
+					),
+					b.BlockStmt(
+						b.ExprStmt(
+							b.AssignExpr(
+								ast.SubAssignOp,
+								b.Identifier("x"),
+								b.NumericLit(1),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `for (let i = 0; i < 10; i += 10) {	x += 1; }`,
+			wantAST: b.Program(
 				b.ForStmt(
 					b.VarStmt(
 						b.VarDecl(
@@ -1016,6 +1429,174 @@ func TestParser_Parse_Loops(t *testing.T) {
 					b.BlockStmt(),
 				),
 			),
+		}, {
+			in: `while (x) { if (y) break; continue; }`,
+			wantAST: b.Program(
+				b.WhileStmt(
+					b.Identifier("x"),
+					b.BlockStmt(
+						b.IfStmt(b.Identifier("y"), b.BreakStmt(nil), nil),
+						b.ContinueStmt(nil),
+					),
+				),
+			),
+		}, {
+			in: `outer: for (;;) { inner: do continue outer; while (x); }`,
+			wantAST: b.Program(
+				b.LabeledStmt(
+					b.Identifier("outer"),
+					b.ForStmt(
+						nil,
+						nil,
+						nil,
+						b.BlockStmt(
+							b.LabeledStmt(
+								b.Identifier("inner"),
+								b.DoWhileStmt(
+									b.Identifier("x"),
+									b.ContinueStmt(b.Identifier("outer")),
+								),
+							),
+						),
+					),
+				),
+			),
+		}, {
+			in: `a: b: while (x) continue a;`,
+			wantAST: b.Program(
+				b.LabeledStmt(
+					b.Identifier("a"),
+					b.LabeledStmt(
+						b.Identifier("b"),
+						b.WhileStmt(
+							b.Identifier("x"),
+							b.ContinueStmt(b.Identifier("a")),
+						),
+					),
+				),
+			),
+		}, {
+			in: `block: { x; break block; }`,
+			wantAST: b.Program(
+				b.LabeledStmt(
+					b.Identifier("block"),
+					b.BlockStmt(
+						b.ExprStmt(b.Identifier("x")),
+						b.BreakStmt(b.Identifier("block")),
+					),
+				),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_Try(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `try { f(); } catch (e) { g(e); }`,
+			wantAST: b.Program(
+				b.TryStmt(
+					b.BlockStmt(
+						b.ExprStmt(b.CallExpr(b.Identifier("f"), nil)),
+					),
+					b.CatchClause(
+						b.Identifier("e"),
+						b.BlockStmt(
+							b.ExprStmt(b.CallExpr(b.Identifier("g"), []ast.Node{b.Identifier("e")})),
+						),
+					),
+					nil,
+				),
+			),
+		}, {
+			in: `try {} finally { x = 1; }`,
+			wantAST: b.Program(
+				b.TryStmt(
+					b.BlockStmt(),
+					nil,
+					b.BlockStmt(
+						b.ExprStmt(b.AssignExpr(ast.SimpleAssignOp, b.Identifier("x"), b.NumericLit(1))),
+					),
+				),
+			),
+		}, {
+			in: `try {} catch (e) {} finally {}`,
+			wantAST: b.Program(
+				b.TryStmt(
+					b.BlockStmt(),
+					b.CatchClause(b.Identifier("e"), b.BlockStmt()),
+					b.BlockStmt(),
+				),
+			),
+		}, {
+			in: `throw "oops", 1;`,
+			wantAST: b.Program(
+				b.ThrowStmt(b.SeqExpr(b.StringLit("oops"), b.NumericLit(1))),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_Switch(t *testing.T) {
+	type test struct {
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `switch (x) { case 1: case 2: a; break; default: b; c; }`,
+			wantAST: b.Program(
+				b.SwitchStmt(
+					b.Identifier("x"),
+					b.SwitchCase(b.NumericLit(1)),
+					b.SwitchCase(
+						b.NumericLit(2),
+						b.ExprStmt(b.Identifier("a")),
+						b.BreakStmt(nil),
+					),
+					b.SwitchCase(
+						nil,
+						b.ExprStmt(b.Identifier("b")),
+						b.ExprStmt(b.Identifier("c")),
+					),
+				),
+			),
+		}, {
+			in: `switch (x, y) {}`,
+			wantAST: b.Program(
+				b.SwitchStmt(b.SeqExpr(b.Identifier("x"), b.Identifier("y"))),
+			),
+		}, {
+			in: `s: switch (x) { default: { break s; } }`,
+			wantAST: b.Program(
+				b.LabeledStmt(
+					b.Identifier("s"),
+					b.SwitchStmt(
+						b.Identifier("x"),
+						b.SwitchCase(
+							nil,
+							b.BlockStmt(b.BreakStmt(b.Identifier("s"))),
+						),
+					),
+				),
+			),
 		},
 	}
 
@@ -1166,7 +1747,7 @@ func TestParser_Parse_Member(t *testing.T) {
 							),
 							b.Identifier("c"),
 						),
-						b.StringLit("d"),
+						b.StringLitRaw(`'d'`, "d"),
 					),
 				),
 			),
@@ -1180,6 +1761,224 @@ func TestParser_Parse_Member(t *testing.T) {
 	}
 }
 
+func TestParser_Parse_Array(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `[];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit()),
+			),
+		}, {
+			in: `[1, "two", [x]];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit(
+					b.NumericLit(1),
+					b.StringLit("two"),
+					b.ArrayLit(b.Identifier("x")),
+				)),
+			),
+		}, {
+			in: `[1, 2,];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit(b.NumericLit(1), b.NumericLit(2))),
+			),
+		}, {
+			in: `[1, , 3];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit(b.NumericLit(1), nil, b.NumericLit(3))),
+			),
+		}, {
+			in: `[, 1, ,];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit(nil, b.NumericLit(1), nil)),
+			),
+		}, {
+			in: `[,];`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrayLit(nil)),
+			),
+		}, {
+			in: `let a = [x = 1, y][0];`,
+			wantAST: b.Program(
+				b.VarStmt(
+					b.VarDecl(
+						b.Identifier("a"),
+						b.MemberExpr(
+							true,
+							b.ArrayLit(
+								b.AssignExpr(ast.SimpleAssignOp, b.Identifier("x"), b.NumericLit(1)),
+								b.Identifier("y"),
+							),
+							b.NumericLit(0),
+						),
+					),
+				),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_Object(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `let o = {};`,
+			wantAST: b.Program(
+				b.VarStmt(b.VarDecl(b.Identifier("o"), b.ObjectLit())),
+			),
+		}, {
+			in: `let o = {a: 1, "b c": x = 2, 3: [], [k + 1]: {},};`,
+			wantAST: b.Program(
+				b.VarStmt(b.VarDecl(b.Identifier("o"), b.ObjectLit(
+					b.Property(ast.InitProperty, false, b.Identifier("a"), b.NumericLit(1)),
+					b.Property(ast.InitProperty, false, b.StringLit("b c"),
+						b.AssignExpr(ast.SimpleAssignOp, b.Identifier("x"), b.NumericLit(2))),
+					b.Property(ast.InitProperty, false, b.NumericLit(3), b.ArrayLit()),
+					b.Property(ast.InitProperty, true,
+						b.BinaryExpr(ast.AddBinaryOp, b.Identifier("k"), b.NumericLit(1)),
+						b.ObjectLit()),
+				))),
+			),
+		}, {
+			in: `f({x, y});`,
+			wantAST: b.Program(
+				b.ExprStmt(b.CallExpr(b.Identifier("f"), []ast.Node{
+					b.ObjectLit(
+						b.Property(ast.ShorthandProperty, false, b.Identifier("x"), b.Identifier("x")),
+						b.Property(ast.ShorthandProperty, false, b.Identifier("y"), b.Identifier("y")),
+					),
+				})),
+			),
+		}, {
+			in: `let o = {get(a, b) { return a; }, n() {}};`,
+			wantAST: b.Program(
+				b.VarStmt(b.VarDecl(b.Identifier("o"), b.ObjectLit(
+					b.Property(ast.MethodProperty, false, b.Identifier("get"),
+						b.FuncDecl(b.Identifier("get"), []ast.Node{b.Identifier("a"), b.Identifier("b")},
+							b.BlockStmt(b.ReturnStmt(b.Identifier("a"))))),
+					b.Property(ast.MethodProperty, false, b.Identifier("n"),
+						b.FuncDecl(b.Identifier("n"), nil, b.BlockStmt())),
+				))),
+			),
+		}, {
+			in: `{} ({}); ({a: 1}).a;`,
+			wantAST: b.Program(
+				b.BlockStmt(),
+				b.ExprStmt(b.ObjectLit()),
+				b.ExprStmt(b.MemberExpr(false,
+					b.ObjectLit(b.Property(ast.InitProperty, false, b.Identifier("a"), b.NumericLit(1))),
+					b.Identifier("a"),
+				)),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
+func TestParser_Parse_LocObject(t *testing.T) {
+	in := `x = {a, m(b) { return b; }};`
+
+	tok := tokenizer.NewScanner(in)
+	node, err := NewParser(tok, b).Parse()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	span := func(n ast.Node) string {
+		return in[n.Loc.Start.Offset:n.Loc.End.Offset]
+	}
+
+	obj := node.Fields.(*ast.Program).Body[0].Fields.(*ast.ExprStmt).Expr.Fields.(*ast.AssignExpr).Right
+	props := obj.Fields.(*ast.ObjectLit).Props
+	assert.Equal(t, `a`, span(props[0]))
+	assert.Equal(t, `a`, span(props[0].Fields.(*ast.Property).Value))
+	assert.Equal(t, `m(b) { return b; }`, span(props[1]))
+	method := props[1].Fields.(*ast.Property).Value
+	assert.Equal(t, `m(b) { return b; }`, span(method))
+	assert.Equal(t, `m`, span(method.Fields.(*ast.FuncDecl).Name))
+}
+
+func TestParser_Parse_FuncExpr(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantAST ast.Node
+	}
+	tests := []test{
+		{
+			in: `let f = def (a, b) { return a; };`,
+			wantAST: b.Program(
+				b.VarStmt(b.VarDecl(b.Identifier("f"),
+					b.FuncExpr(nil, []ast.Node{b.Identifier("a"), b.Identifier("b")},
+						b.BlockStmt(b.ReturnStmt(b.Identifier("a")))),
+				)),
+			),
+		}, {
+			in: `(def fact(n) {})(5);`,
+			wantAST: b.Program(
+				b.ExprStmt(b.CallExpr(
+					b.FuncExpr(b.Identifier("fact"), []ast.Node{b.Identifier("n")}, b.BlockStmt()),
+					[]ast.Node{b.NumericLit(5)},
+				)),
+			),
+		}, {
+			in: `f(x => x + 1, () => {}, (a) => a, (a, b) => c = a, b);`,
+			wantAST: b.Program(
+				b.ExprStmt(b.CallExpr(b.Identifier("f"), []ast.Node{
+					b.ArrowFunc([]ast.Node{b.Identifier("x")},
+						b.BinaryExpr(ast.AddBinaryOp, b.Identifier("x"), b.NumericLit(1))),
+					b.ArrowFunc(nil, b.BlockStmt()),
+					b.ArrowFunc([]ast.Node{b.Identifier("a")}, b.Identifier("a")),
+					b.ArrowFunc([]ast.Node{b.Identifier("a"), b.Identifier("b")},
+						b.AssignExpr(ast.SimpleAssignOp, b.Identifier("c"), b.Identifier("a"))),
+					b.Identifier("b"),
+				})),
+			),
+		}, {
+			in: `x => y => ({x, y});`,
+			wantAST: b.Program(
+				b.ExprStmt(b.ArrowFunc([]ast.Node{b.Identifier("x")},
+					b.ArrowFunc([]ast.Node{b.Identifier("y")}, b.ObjectLit(
+						b.Property(ast.ShorthandProperty, false, b.Identifier("x"), b.Identifier("x")),
+						b.Property(ast.ShorthandProperty, false, b.Identifier("y"), b.Identifier("y")),
+					)),
+				)),
+			),
+		}, {
+			in: `(a, b);`,
+			wantAST: b.Program(
+				b.ExprStmt(b.SeqExpr(b.Identifier("a"), b.Identifier("b"))),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			testOk(t, tc.in, tc.wantAST)
+		})
+	}
+}
+
 func TestParser_Parse_FuncCalls(t *testing.T) {
 	type test struct {
 		name    string
@@ -1581,13 +2380,465 @@ func TestParser_Parse_New(t *testing.T) {
 	}
 }
 
-func testOk(t *testing.T, in string, wantAST ast.Node) {
-	tok := tokenizer.NewTokenizer(tokenizer.DefaultRules, in)
-	p := NewParser(tok, b)
+func TestParser_Parse_Loc(t *testing.T) {
+	in := "let x = 1;\nfoo(x,\n  \"a\");\n"
+
+	tok := tokenizer.NewScanner(in)
+	node, err := NewParser(tok, b).Parse()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	loc := func(startLine, startCol, startOff, endLine, endCol, endOff int) ast.Loc {
+		return ast.Loc{
+			Start: ast.Position{Offset: startOff, Line: startLine, Column: startCol},
+			End:   ast.Position{Offset: endOff, Line: endLine, Column: endCol},
+		}
+	}
+
+	prog := node.Fields.(*ast.Program)
+	assert.Equal(t, loc(1, 1, 0, 3, 8, 25), node.Loc)
+
+	varStmt := prog.Body[0]
+	assert.Equal(t, loc(1, 1, 0, 1, 11, 10), varStmt.Loc)
+	decl := varStmt.Fields.(*ast.VarStmt).Decls[0]
+	assert.Equal(t, loc(1, 5, 4, 1, 10, 9), decl.Loc)
+	assert.Equal(t, loc(1, 5, 4, 1, 6, 5), decl.Fields.(*ast.VarDecl).ID.Loc)
+	assert.Equal(t, loc(1, 9, 8, 1, 10, 9), decl.Fields.(*ast.VarDecl).Init.Loc)
+
+	exprStmt := prog.Body[1]
+	assert.Equal(t, loc(2, 1, 11, 3, 8, 25), exprStmt.Loc)
+	call := exprStmt.Fields.(*ast.ExprStmt).Expr
+	assert.Equal(t, loc(2, 1, 11, 3, 7, 24), call.Loc)
+	assert.Equal(t, loc(3, 3, 20, 3, 6, 23), call.Fields.(*ast.CallExpr).Args[1].Loc)
+}
+
+func TestParser_Parse_LocBinary(t *testing.T) {
+	in := `a.b[0] = -x * (y + 2);`
+
+	tok := tokenizer.NewScanner(in)
+	node, err := NewParser(tok, b).Parse()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	span := func(n ast.Node) string {
+		return in[n.Loc.Start.Offset:n.Loc.End.Offset]
+	}
+
+	assign := node.Fields.(*ast.Program).Body[0].Fields.(*ast.ExprStmt).Expr
+	assert.Equal(t, `a.b[0] = -x * (y + 2)`, span(assign))
+	fields := assign.Fields.(*ast.AssignExpr)
+	assert.Equal(t, `a.b[0]`, span(fields.Left))
+	assert.Equal(t, `a.b`, span(fields.Left.Fields.(*ast.MemberExpr).Obj))
+	assert.Equal(t, `-x * (y + 2)`, span(fields.Right))
+	assert.Equal(t, `y + 2`, span(fields.Right.Fields.(*ast.BinaryExpr).Right))
+}
+
+func TestParser_Parse_Error(t *testing.T) {
+	type test struct {
+		in      string
+		wantErr string
+	}
+	tests := []test{
+		{
+			in:      `let x = 1`,
+			wantErr: `1:10: unexpected end of input, expected: ";"`,
+		}, {
+			in:      `a ? b;`,
+			wantErr: `1:6: unexpected token, ";(;)", expected: ":"`,
+		}, {
+			in:      `(a ? b : c) = 1;`,
+			wantErr: `1:2: invalid lvalue in assignment: ConditionalExprType`,
+		}, {
+			in:      "42;\n  (1 + );",
+			wantErr: `2:8: unexpected token, ")())", expected: "PrimaryExpr"`,
+		}, {
+			in:      `1 = 2;`,
+			wantErr: `1:1: invalid lvalue in assignment: NumericLitType`,
+		}, {
+			in:      `++f();`,
+			wantErr: `1:3: invalid lvalue in assignment: CallExprType`,
+		}, {
+			in:      `(a + b)--;`,
+			wantErr: `1:2: invalid lvalue in assignment: BinaryExprType`,
+		}, {
+			in:      `a++ ++;`,
+			wantErr: `1:5: unexpected token, "UpdateOp(++)", expected: ";"`,
+		}, {
+			in:      `let a = @;`,
+			wantErr: `1:9: unexpected character "@"`,
+		}, {
+			in:      `{`,
+			wantErr: `1:2: unexpected end of input, expected: "}"`,
+		}, {
+			in:      `let s = "abc\q";`,
+			wantErr: `1:13: invalid escape sequence \q`,
+		}, {
+			in:      `"\u12x";`,
+			wantErr: `1:2: invalid escape sequence \u12`,
+		}, {
+			in:      `"\u{110000}";`,
+			wantErr: `1:2: invalid escape sequence \u{110000}`,
+		}, {
+			in:      `"\uD83D";`,
+			wantErr: `1:2: invalid escape sequence \uD83D`,
+		}, {
+			in:      "let s = 'abc;\n",
+			wantErr: `1:9: unterminated string literal`,
+		}, {
+			in:      `9223372036854775808;`,
+			wantErr: `1:1: numeric literal 9223372036854775808 overflows int64`,
+		}, {
+			in:      `0x1_0000_0000_0000_0000;`,
+			wantErr: `1:1: numeric literal 0x1_0000_0000_0000_0000 overflows int64`,
+		}, {
+			in:      `1e400;`,
+			wantErr: `1:1: numeric literal 1e400 overflows float64`,
+		}, {
+			in:      `[1 2];`,
+			wantErr: `1:4: unexpected token, "Number(2)", expected: ","`,
+		}, {
+			in:      `{a: 1};`,
+			wantErr: `1:6: unexpected token, "}(})", expected: ";"`,
+		}, {
+			in:      `({a 1});`,
+			wantErr: `1:5: unexpected token, "Number(1)", expected: ":"`,
+		}, {
+			in:      `({[a]() {}});`,
+			wantErr: `1:6: unexpected token, "((()", expected: ":"`,
+		}, {
+			in:      `({-1: a});`,
+			wantErr: `1:3: unexpected token, "AdditiveOp(-)", expected: "Property"`,
+		}, {
+			in:      `(a, 1) => a;`,
+			wantErr: `1:5: invalid arrow function parameter: NumericLitType`,
+		}, {
+			in:      `();`,
+			wantErr: `1:3: unexpected token, ";(;)", expected: "=>"`,
+		}, {
+			in:      `def () {};`,
+			wantErr: `1:5: unexpected token, "((()", expected: "Identifier"`,
+		}, {
+			in:      `if (x) break;`,
+			wantErr: `1:8: break outside of loop or switch`,
+		}, {
+			in:      `while (x) { def f() { continue; } }`,
+			wantErr: `1:23: continue outside of loop`,
+		}, {
+			in:      `while (x) break y;`,
+			wantErr: `1:17: undefined label: y`,
+		}, {
+			in:      `a: while (x) { () => { break a; }; }`,
+			wantErr: `1:30: undefined label: a`,
+		}, {
+			in:      `a: { while (x) continue a; }`,
+			wantErr: `1:25: continue label a does not denote a loop`,
+		}, {
+			in:      `a: while (x) { a: y; }`,
+			wantErr: `1:16: label a already defined`,
+		}, {
+			in:      `try {} let x;`,
+			wantErr: `1:8: missing catch or finally after try`,
+		}, {
+			in:      `try {} catch {}`,
+			wantErr: `1:14: unexpected token, "{({)", expected: "("`,
+		}, {
+			in:      `throw;`,
+			wantErr: `1:6: unexpected token, ";(;)", expected: "PrimaryExpr"`,
+		}, {
+			in:      `switch (x) { default: a; case 1: default: }`,
+			wantErr: `1:34: multiple default clauses in switch`,
+		}, {
+			in:      `switch (x) { a; }`,
+			wantErr: `1:14: unexpected token, "Identifier(a)", expected: "case"`,
+		}, {
+			in:      `while (x) { switch (y) { case 1: continue; } } switch (y) { case 1: continue; }`,
+			wantErr: `1:69: continue outside of loop`,
+		}, {
+			in:      `(a): x;`,
+			wantErr: `1:4: unexpected token, ":(:)", expected: ";"`,
+		},
+	}
+
+	for _, tc := range tests {
+		for _, tk := range tokenizers {
+			t.Run(tc.in+"/"+tk.name, func(t *testing.T) {
+				_, err := NewParser(tk.new(tc.in), b).Parse()
+				assert.EqualError(t, err, tc.wantErr)
+			})
+		}
+	}
+}
+
+func TestParser_ParseRecover(t *testing.T) {
+	type test struct {
+		in       string
+		wantAST  ast.Node
+		wantErrs []string
+	}
+	tests := []test{
+		{
+			in:      ``,
+			wantAST: b.Program(),
+		}, {
+			in: `let x = ; let y = 2;`,
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.VarStmt(b.VarDecl(b.Identifier("y"), b.NumericLit(2))),
+			),
+			wantErrs: []string{
+				`1:9: unexpected token, ";(;)", expected: "PrimaryExpr"`,
+			},
+		}, {
+			in: `break; continue x; let y = 2;`,
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.BadStmt(),
+				b.VarStmt(b.VarDecl(b.Identifier("y"), b.NumericLit(2))),
+			),
+			wantErrs: []string{
+				`1:1: break outside of loop or switch`,
+				`1:17: undefined label: x`,
+			},
+		}, {
+			in: `try {} throw 1; let y = 2;`,
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.ThrowStmt(b.NumericLit(1)),
+				b.VarStmt(b.VarDecl(b.Identifier("y"), b.NumericLit(2))),
+			),
+			wantErrs: []string{
+				`1:8: missing catch or finally after try`,
+			},
+		}, {
+			in: `switch (x) { case 1: a b; case 2: break; }`,
+			wantAST: b.Program(
+				b.SwitchStmt(
+					b.Identifier("x"),
+					b.SwitchCase(b.NumericLit(1), b.BadStmt()),
+					b.SwitchCase(b.NumericLit(2), b.BreakStmt(nil)),
+				),
+			),
+			wantErrs: []string{
+				`1:24: unexpected token, "Identifier(b)", expected: ";"`,
+			},
+		}, {
+			in: "let x = \"abc;\nlet y = 2;",
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.VarStmt(b.VarDecl(b.Identifier("y"), b.NumericLit(2))),
+			),
+			wantErrs: []string{
+				`1:9: unterminated string literal`,
+				`2:1: unexpected token, "let(let)", expected: "PrimaryExpr"`,
+			},
+		}, {
+			in: `
+foo(;
+{
+	a b;
+	c;
+}
+d;
+`,
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.BlockStmt(
+					b.BadStmt(),
+					b.ExprStmt(b.Identifier("c")),
+				),
+				b.ExprStmt(b.Identifier("d")),
+			),
+			wantErrs: []string{
+				`2:5: unexpected token, ";(;)", expected: "PrimaryExpr"`,
+				`4:4: unexpected token, "Identifier(b)", expected: ";"`,
+			},
+		}, {
+			in: `def f() { return 1 } f();`,
+			wantAST: b.Program(
+				b.FuncDecl(b.Identifier("f"), nil, b.BlockStmt(
+					b.BadStmt(),
+				)),
+				b.ExprStmt(b.CallExpr(b.Identifier("f"), nil)),
+			),
+			wantErrs: []string{
+				`1:20: unexpected token, "}(})", expected: ";"`,
+			},
+		}, {
+			in: `} @ 1; while (x) { y = 1;`,
+			wantAST: b.Program(
+				b.BadStmt(),
+				b.ExprStmt(b.NumericLit(1)),
+				b.WhileStmt(
+					b.Identifier("x"),
+					b.BlockStmt(
+						b.ExprStmt(b.AssignExpr(ast.SimpleAssignOp, b.Identifier("y"), b.NumericLit(1))),
+					),
+				),
+			),
+			wantErrs: []string{
+				`1:1: unexpected token, "}(})", expected: "PrimaryExpr"`,
+				`1:3: unexpected character "@"`,
+				`1:26: unexpected end of input, expected: "}"`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, tk := range tokenizers {
+			t.Run(tc.in+"/"+tk.name, func(t *testing.T) {
+				node, errs := NewParser(tk.new(tc.in), b).ParseRecover()
+
+				var gotErrs []string
+				for _, err := range errs {
+					gotErrs = append(gotErrs, err.Error())
+				}
+				assert.Equal(t, tc.wantErrs, gotErrs)
+
+				clearLoc(node)
+				if !assert.Exactly(t, tc.wantAST, node) {
+					assert.Exactly(t, dumpJSON(t, tc.wantAST), dumpJSON(t, node))
+				}
+			})
+		}
+	}
+}
+
+func TestParser_ParseRecover_Reader(t *testing.T) {
+	r := io.MultiReader(
+		iotest.OneByteReader(strings.NewReader("let x = 1;\nprint(x);\nlet")),
+		iotest.ErrReader(errors.New("read error")),
+	)
+	node, errs := NewParser(tokenizer.NewReaderScanner(r), b).ParseRecover()
+	clearLoc(node)
+
+	assert.Exactly(t, b.Program(
+		b.VarStmt(b.VarDecl(b.Identifier("x"), b.NumericLit(1))),
+		b.ExprStmt(b.CallExpr(b.Identifier("print"), []ast.Node{b.Identifier("x")})),
+		b.BadStmt(),
+	), node)
+	assert.Equal(t, []string{
+		"3:1: read error",
+		`3:4: unexpected end of input, expected: "Identifier"`,
+	}, errorStrings(errs))
+}
+
+func errorStrings(errs ErrorList) []string {
+	var result []string
+	for _, err := range errs {
+		result = append(result, err.Error())
+	}
+	return result
+}
+
+func TestParser_KeepComments(t *testing.T) {
+	in := "// line\nx; /* block\n*/"
+
+	p := NewParser(tokenizer.NewScanner(in), b)
+	p.KeepComments()
 	node, err := p.Parse()
-	assert.NoError(t, err)
-	if !assert.Exactly(t, wantAST, node) {
-		assert.Exactly(t, dumpJSON(t, wantAST), dumpJSON(t, node))
+	require.NoError(t, err)
+
+	assert.Equal(t, []*ast.Comment{
+		{
+			Text: "// line",
+			Loc: ast.Loc{
+				Start: ast.Position{Offset: 0, Line: 1, Column: 1},
+				End:   ast.Position{Offset: 7, Line: 1, Column: 8},
+			},
+		}, {
+			Text: "/* block\n*/",
+			Loc: ast.Loc{
+				Start: ast.Position{Offset: 11, Line: 2, Column: 4},
+				End:   ast.Position{Offset: 22, Line: 3, Column: 3},
+			},
+		},
+	}, node.Fields.(*ast.Program).Comments)
+
+	node, err = NewParser(tokenizer.NewScanner(in), b).Parse()
+	require.NoError(t, err)
+	assert.Nil(t, node.Fields.(*ast.Program).Comments)
+}
+
+func TestParser_KeepComments_Doc(t *testing.T) {
+	in := `// Package comment.
+
+// x is documented.
+// Over two lines.
+let x = 1;
+
+let y; // not a doc of z
+let z;
+
+/*
+ * Point is a point.
+ */
+class Point {
+	// constructor is documented too.
+	def constructor() {}
+
+	/* m */ def m() {}
+}
+
+// A comment separated by an empty line.
+
+def f() {
+	// Not a doc.
+	g();
+}`
+
+	p := NewParser(tokenizer.NewScanner(in), b)
+	p.KeepComments()
+	node, err := p.Parse()
+	require.NoError(t, err)
+
+	body := node.Fields.(*ast.Program).Body
+	class := body[3].Fields.(*ast.ClassDecl)
+	methods := class.Body.Fields.(*ast.BlockStmt).Body
+
+	for _, tc := range []struct {
+		name    string
+		doc     *ast.CommentGroup
+		wantDoc string
+	}{
+		{"x", body[0].Fields.(*ast.VarStmt).Doc, "x is documented.\nOver two lines."},
+		{"y", body[1].Fields.(*ast.VarStmt).Doc, ""},
+		{"z", body[2].Fields.(*ast.VarStmt).Doc, ""},
+		{"Point", class.Doc, " * Point is a point."},
+		{"constructor", methods[0].Fields.(*ast.FuncDecl).Doc, "constructor is documented too."},
+		{"m", methods[1].Fields.(*ast.FuncDecl).Doc, " m"},
+		{"f", body[4].Fields.(*ast.FuncDecl).Doc, ""},
+	} {
+		assert.Equal(t, tc.wantDoc, tc.doc.Text(), tc.name)
+	}
+	assert.Len(t, body[0].Fields.(*ast.VarStmt).Doc.List, 2)
+
+	node, err = NewParser(tokenizer.NewScanner(in), b).Parse()
+	require.NoError(t, err)
+	assert.Nil(t, node.Fields.(*ast.Program).Body[0].Fields.(*ast.VarStmt).Doc)
+}
+
+// tokenizers are the tokenizers the parser tables run against, the parser
+// must give the same results with each of them.
+var tokenizers = []struct {
+	name string
+	new  func(in string) Tokenizer
+}{
+	{"Scanner", func(in string) Tokenizer { return tokenizer.NewScanner(in) }},
+	{"Tokenizer", func(in string) Tokenizer { return tokenizer.NewTokenizer(tokenizer.DefaultRules, in) }},
+}
+
+func testOk(t *testing.T, in string, wantAST ast.Node) {
+	for _, tk := range tokenizers {
+		t.Run(tk.name, func(t *testing.T) {
+			node, err := NewParser(tk.new(in), b).Parse()
+			assert.NoError(t, err)
+			clearLoc(node)
+			if !assert.Exactly(t, wantAST, node) {
+				assert.Exactly(t, dumpJSON(t, wantAST), dumpJSON(t, node))
+			}
+		})
 	}
 }
 
@@ -1600,3 +2851,17 @@ func dumpJSON(t *testing.T, node ast.Node) string {
 	assert.NoError(t, err)
 	return buf.String()
 }
+
+// clearLoc resets locations in the tree so that it can be compared with
+// trees made by the Builder.
+func clearLoc(node ast.Node) {
+	if node == nil {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			n.Loc = ast.Loc{}
+		}
+		return true
+	})
+}
@@ -0,0 +1,15 @@
+package printer
+
+import (
+	"fmt"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+type ErrUnexpectedNode struct {
+	Node ast.Node
+}
+
+func (e *ErrUnexpectedNode) Error() string {
+	return fmt.Sprintf("can't print node %s", e.Node.Type)
+}
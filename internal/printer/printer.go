@@ -0,0 +1,898 @@
+package printer
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+// Operator precedence, from the loosest to the tightest binding. It mirrors
+// the order of the expression productions in the parser.
+const (
+	precLowest = iota
+	precSeq
+	precAssign
+	precCond
+	precOr
+	precAnd
+	precBitOr
+	precBitXor
+	precBitAnd
+	precEqual
+	precRel
+	precShift
+	precAdd
+	precMul
+	precUnary
+	precExp
+	precUpdate
+	precCall
+	precMember
+)
+
+var binaryPrec = map[ast.BinaryOp]int{
+	ast.BitOrBinaryOp:      precBitOr,
+	ast.BitXorBinaryOp:     precBitXor,
+	ast.BitAndBinaryOp:     precBitAnd,
+	ast.EqBinaryOp:         precEqual,
+	ast.NeqBinaryOp:        precEqual,
+	ast.StrictEqBinaryOp:   precEqual,
+	ast.StrictNeqBinaryOp:  precEqual,
+	ast.GtBinaryOp:         precRel,
+	ast.LtBinaryOp:         precRel,
+	ast.GteBinaryOp:        precRel,
+	ast.LteBinaryOp:        precRel,
+	ast.InstanceofBinaryOp: precRel,
+	ast.InBinaryOp:         precRel,
+	ast.ShlBinaryOp:        precShift,
+	ast.ShrBinaryOp:        precShift,
+	ast.UShrBinaryOp:       precShift,
+	ast.AddBinaryOp:        precAdd,
+	ast.SubBinaryOp:        precAdd,
+	ast.MulBinaryOp:        precMul,
+	ast.DivBinaryOp:        precMul,
+	ast.ModBinaryOp:        precMul,
+	ast.ExpBinaryOp:        precExp,
+}
+
+var logicalPrec = map[ast.LogicalOp]int{
+	ast.OrLogicalOp:  precOr,
+	ast.AndLogicalOp: precAnd,
+}
+
+// Config controls the layout of the printed source code.
+type Config struct {
+	// LineWidth is the maximum width of a line, argument lists which don't
+	// fit are broken one argument per line. Zero disables the breaking.
+	LineWidth int
+	// TabWidth is the width of the indentation tab used to measure lines.
+	TabWidth int
+}
+
+// DefaultConfig is the configuration used by Fprint.
+var DefaultConfig = Config{LineWidth: 80, TabWidth: 4}
+
+// Fprint writes the canonical source code of the node to w using the
+// DefaultConfig.
+func Fprint(w io.Writer, node ast.Node) error {
+	return DefaultConfig.Fprint(w, node)
+}
+
+// Fprint writes the canonical source code of the node to w. A program is
+// printed one statement per line, blocks are indented with tabs and
+// expressions are parenthesized only where the operator precedence requires
+// it, so that parsing the output gives back the same tree.
+//
+// The comments of the program, if the parser kept them, are printed between
+// the statements they were found at. Single blank lines between statements
+// are kept as well.
+func (c *Config) Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{Config: *c}
+	if prog, ok := node.Fields.(*ast.Program); ok {
+		p.comments = prog.Comments
+	}
+	p.node(node)
+	if p.err != nil {
+		return p.err
+	}
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	Config
+	buf    bytes.Buffer
+	indent int
+	// err is the first error encountered, printing goes on after it
+	err error
+
+	// comments are the comments which are not printed yet
+	comments []*ast.Comment
+	// lastLine is the source line of the last statement or comment
+	// printed, it is zero when unknown
+	lastLine int
+}
+
+func (p *printer) node(n ast.Node) {
+	switch n.Type {
+	case ast.ProgramType:
+		// The comments after the last statement are outside of the program
+		p.stmtList(n.Fields.(*ast.Program).Body, ast.Position{Offset: math.MaxInt}, false)
+		if p.buf.Len() > 0 {
+			p.print("\n")
+		}
+	case ast.ExprStmtType, ast.BlockStmtType, ast.EmptyStmtType, ast.VarStmtType,
+		ast.IfStmtType, ast.WhileStmtType, ast.DoWhileStmtType, ast.ForStmtType,
+		ast.BreakStmtType, ast.ContinueStmtType, ast.LabeledStmtType,
+		ast.TryStmtType, ast.CatchClauseType, ast.ThrowStmtType, ast.SwitchStmtType, ast.SwitchCaseType,
+		ast.FuncDeclType, ast.ReturnStmtType, ast.ClassDeclType, ast.BadStmtType:
+		p.stmt(n)
+	default:
+		p.expr(n, precLowest)
+	}
+}
+
+// stmtList prints the statements one per line, each one is preceded by the
+// comments found before it in the source. The comments on the line the
+// statement ends at are kept on that line. The comments before the end
+// position are printed after the last statement. If separate is set the
+// statements are separated with an empty line.
+func (p *printer) stmtList(list []ast.Node, end ast.Position, separate bool) {
+	for i, stmt := range list {
+		blank := separate && i > 0
+		for len(p.comments) > 0 && p.comments[0].Loc.Start.Offset < stmt.Loc.Start.Offset {
+			p.linebreak(p.comments[0].Loc.Start.Line, blank)
+			p.comment()
+			blank = false
+		}
+
+		p.linebreak(stmt.Loc.Start.Line, blank)
+		p.stmt(stmt)
+		p.lastLine = stmt.Loc.End.Line
+		p.trailingComments(stmt, end)
+	}
+
+	for len(p.comments) > 0 && p.comments[0].Loc.Start.Offset < end.Offset {
+		p.linebreak(p.comments[0].Loc.Start.Line, false)
+		p.comment()
+	}
+}
+
+// trailingComments prints the comments found inside the statement and on
+// the line it ends at after it. A line comment runs to the end of the line,
+// so the block comments are printed before it and each further line comment
+// is moved to a line of its own.
+func (p *printer) trailingComments(stmt ast.Node, end ast.Position) {
+	var lineComments []*ast.Comment
+	for len(p.comments) > 0 && (p.comments[0].Loc.Start.Offset < stmt.Loc.End.Offset ||
+		p.comments[0].Loc.Start.Line == stmt.Loc.End.Line) &&
+		p.comments[0].Loc.Start.Offset < end.Offset {
+		if c := p.comments[0]; strings.HasPrefix(c.Text, "//") {
+			lineComments = append(lineComments, c)
+			p.comments = p.comments[1:]
+			continue
+		}
+		p.print(" ")
+		p.comment()
+	}
+
+	for i, c := range lineComments {
+		if i == 0 {
+			p.print(" ")
+		} else {
+			p.newline()
+		}
+		p.printComment(c)
+	}
+}
+
+// comment prints the next comment.
+func (p *printer) comment() {
+	c := p.comments[0]
+	p.comments = p.comments[1:]
+	p.printComment(c)
+}
+
+// printComment prints the comment keeping track of the line it ends at.
+func (p *printer) printComment(c *ast.Comment) {
+	p.print(c.Text)
+	if c.Loc.End.Line > p.lastLine {
+		p.lastLine = c.Loc.End.Line
+	}
+}
+
+// linebreak starts a new line for the item at the source line. An empty
+// line is inserted before it if blank is set or if there is one in the
+// source. Nothing is done at the beginning of the output.
+func (p *printer) linebreak(line int, blank bool) {
+	if p.buf.Len() == 0 {
+		return
+	}
+	if blank || (p.lastLine > 0 && line-p.lastLine > 1) {
+		p.print("\n")
+	}
+	p.newline()
+}
+
+func (p *printer) stmt(n ast.Node) {
+	switch f := n.Fields.(type) {
+	case *ast.ExprStmt:
+		if startsStmt(f.Expr) {
+			// Otherwise it would be parsed as a statement
+			p.print("(")
+			p.expr(f.Expr, precLowest)
+			p.print(")")
+		} else {
+			p.expr(f.Expr, precLowest)
+		}
+		p.print(";")
+
+	case *ast.BlockStmt:
+		p.block(n, false)
+
+	case *ast.EmptyStmt:
+		p.print(";")
+
+	case *ast.VarStmt:
+		p.varStmt(f)
+		p.print(";")
+
+	case *ast.IfStmt:
+		p.ifStmt(f)
+
+	case *ast.WhileStmt:
+		p.print("while (")
+		p.expr(f.Cond, precLowest)
+		p.print(")")
+		p.body(f.Body)
+
+	case *ast.DoWhileStmt:
+		p.print("do")
+		p.body(f.Body)
+		if f.Body.Type == ast.BlockStmtType {
+			p.print(" ")
+		} else {
+			p.newline()
+		}
+		p.print("while (")
+		p.expr(f.Cond, precLowest)
+		p.print(");")
+
+	case *ast.ForStmt:
+		p.print("for (")
+		if f.Init != nil {
+			if f.Init.Type == ast.VarStmtType {
+				p.varStmt(f.Init.Fields.(*ast.VarStmt))
+			} else {
+				p.expr(f.Init, precLowest)
+			}
+		}
+		p.print(";")
+		if f.Cond != nil {
+			p.print(" ")
+			p.expr(f.Cond, precLowest)
+		}
+		p.print(";")
+		if f.Step != nil {
+			p.print(" ")
+			p.expr(f.Step, precLowest)
+		}
+		p.print(")")
+		p.body(f.Body)
+
+	case *ast.BreakStmt:
+		p.jumpStmt("break", f.Label)
+
+	case *ast.ContinueStmt:
+		p.jumpStmt("continue", f.Label)
+
+	case *ast.LabeledStmt:
+		p.expr(f.Label, precLowest)
+		p.print(": ")
+		p.stmt(f.Body)
+
+	case *ast.TryStmt:
+		p.print("try ")
+		p.stmt(f.Block)
+		if f.Handler != nil {
+			p.print(" ")
+			p.stmt(f.Handler)
+		}
+		if f.Finalizer != nil {
+			p.print(" finally ")
+			p.stmt(f.Finalizer)
+		}
+
+	case *ast.CatchClause:
+		p.print("catch (")
+		p.expr(f.Param, precLowest)
+		p.print(") ")
+		p.stmt(f.Body)
+
+	case *ast.ThrowStmt:
+		p.print("throw ")
+		p.expr(f.Arg, precLowest)
+		p.print(";")
+
+	case *ast.SwitchStmt:
+		p.switchStmt(n, f)
+
+	case *ast.SwitchCase:
+		if f.Test != nil {
+			p.print("case ")
+			p.expr(f.Test, precLowest)
+			p.print(":")
+			p.lastLine = f.Test.Loc.End.Line
+		} else {
+			p.print("default:")
+			p.lastLine = n.Loc.Start.Line
+		}
+		p.indent++
+		p.stmtList(f.Body, n.Loc.End, false)
+		p.indent--
+
+	case *ast.FuncDecl:
+		p.print("def ")
+		p.expr(f.Name, precLowest)
+		p.print("(")
+		p.exprList(f.Params)
+		p.print(") ")
+		p.stmt(f.Body)
+
+	case *ast.ReturnStmt:
+		p.print("return")
+		if f.Arg != nil {
+			p.print(" ")
+			p.expr(f.Arg, precLowest)
+		}
+		p.print(";")
+
+	case *ast.ClassDecl:
+		p.print("class ")
+		p.expr(f.ID, precLowest)
+		if f.Super != nil {
+			p.print(" extends ")
+			p.expr(f.Super, precLowest)
+		}
+		p.print(" ")
+		p.block(f.Body, true)
+
+	default:
+		p.error(n)
+	}
+}
+
+// block prints the statements enclosed in curly braces. Class bodies have
+// their methods separated by an empty line.
+func (p *printer) block(n ast.Node, separate bool) {
+	p.print("{")
+	p.lastLine = n.Loc.Start.Line
+	start := p.buf.Len()
+	for len(p.comments) > 0 && p.comments[0].Loc.Start.Line == n.Loc.Start.Line &&
+		p.comments[0].Loc.Start.Offset > n.Loc.Start.Offset &&
+		p.comments[0].Loc.Start.Offset < n.Loc.End.Offset {
+		p.print(" ")
+		p.comment()
+	}
+	p.indent++
+	p.stmtList(n.Fields.(*ast.BlockStmt).Body, n.Loc.End, separate)
+	p.indent--
+	if p.buf.Len() > start {
+		p.newline()
+	}
+	p.print("}")
+	p.lastLine = n.Loc.End.Line
+}
+
+// body prints the body of a compound statement. Blocks stay on the line of
+// the statement header, other statements are moved to the next line.
+func (p *printer) body(n ast.Node) {
+	switch n.Type {
+	case ast.BlockStmtType:
+		p.print(" ")
+		p.stmt(n)
+	case ast.EmptyStmtType:
+		p.stmt(n)
+	default:
+		p.indent++
+		p.newline()
+		p.stmt(n)
+		p.indent--
+	}
+}
+
+func (p *printer) ifStmt(f *ast.IfStmt) {
+	p.print("if (")
+	p.expr(f.Cond, precLowest)
+	p.print(")")
+
+	cons := f.Cons
+	if f.Alt != nil && cons.Type == ast.IfStmtType && cons.Fields.(*ast.IfStmt).Alt == nil {
+		// Without the braces the else would belong to the inner if
+		cons = ast.Builder{}.BlockStmt(cons)
+	}
+	p.body(cons)
+
+	if f.Alt == nil {
+		return
+	}
+
+	if cons.Type == ast.BlockStmtType {
+		p.print(" ")
+	} else {
+		p.newline()
+	}
+	p.print("else")
+
+	if f.Alt.Type == ast.IfStmtType {
+		p.print(" ")
+		p.stmt(f.Alt)
+	} else {
+		p.body(f.Alt)
+	}
+}
+
+// switchStmt prints the cases of the switch statement indented in the
+// braces as the statements of a block, the bodies of the cases are
+// indented once more.
+func (p *printer) switchStmt(n ast.Node, f *ast.SwitchStmt) {
+	p.print("switch (")
+	p.expr(f.Disc, precLowest)
+	p.print(") {")
+	p.lastLine = f.Disc.Loc.End.Line
+	start := p.buf.Len()
+	p.indent++
+	p.stmtList(f.Cases, n.Loc.End, false)
+	p.indent--
+	if p.buf.Len() > start {
+		p.newline()
+	}
+	p.print("}")
+	p.lastLine = n.Loc.End.Line
+}
+
+// jumpStmt prints the break or continue statement with the optional label.
+func (p *printer) jumpStmt(keyword string, label ast.Node) {
+	p.print(keyword)
+	if label != nil {
+		p.print(" ")
+		p.expr(label, precLowest)
+	}
+	p.print(";")
+}
+
+func (p *printer) varStmt(f *ast.VarStmt) {
+	p.print("let ")
+	for i, decl := range f.Decls {
+		if i > 0 {
+			p.print(", ")
+		}
+		d := decl.Fields.(*ast.VarDecl)
+		p.expr(d.ID, precLowest)
+		if d.Init != nil {
+			p.print(" = ")
+			p.expr(d.Init, precAssign)
+		}
+	}
+}
+
+// expr prints the expression, it is enclosed in parentheses if it binds
+// looser than prec.
+func (p *printer) expr(n ast.Node, prec int) {
+	if exprPrec(n) < prec {
+		p.print("(")
+		defer p.print(")")
+	}
+
+	switch f := n.Fields.(type) {
+	case *ast.NumericLit:
+		p.print(numericLit(f))
+
+	case *ast.StringLit:
+		if f.Raw != "" {
+			p.print(f.Raw)
+		} else {
+			p.print(ast.Quote(f.Value))
+		}
+
+	case *ast.BoolLit:
+		p.print(strconv.FormatBool(f.Value))
+
+	case *ast.NullLit:
+		p.print("null")
+
+	case *ast.ArrayLit:
+		p.print("[")
+		for i, e := range f.Elements {
+			if i > 0 {
+				p.print(", ")
+			}
+			if e != nil {
+				p.expr(e, precAssign)
+			}
+		}
+		if n := len(f.Elements); n > 0 && f.Elements[n-1] == nil {
+			// The trailing hole needs its comma to be kept
+			p.print(",")
+		}
+		p.print("]")
+
+	case *ast.ObjectLit:
+		p.objectLit(f)
+
+	case *ast.FuncExpr:
+		p.print("def ")
+		if f.Name != nil {
+			p.expr(f.Name, precLowest)
+		}
+		p.print("(")
+		p.exprList(f.Params)
+		p.print(") ")
+		p.stmt(f.Body)
+
+	case *ast.ArrowFunc:
+		p.print("(")
+		p.exprList(f.Params)
+		p.print(") => ")
+		switch {
+		case f.Body.Type == ast.BlockStmtType:
+			p.stmt(f.Body)
+		case leftmost(f.Body).Type == ast.ObjectLitType:
+			// Otherwise the '{' would start a block
+			p.print("(")
+			p.expr(f.Body, precLowest)
+			p.print(")")
+		default:
+			p.expr(f.Body, precAssign)
+		}
+
+	case *ast.Identifier:
+		p.print(f.Name)
+
+	case *ast.ThisExpr:
+		p.print("this")
+
+	case *ast.SuperCall:
+		p.print("super")
+
+	case *ast.SeqExpr:
+		for i, e := range f.Body {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.expr(e, precAssign)
+		}
+
+	case *ast.AssignExpr:
+		p.expr(f.Left, precMember)
+		p.print(" " + f.Op.String() + " ")
+		p.expr(f.Right, precAssign)
+
+	case *ast.ConditionalExpr:
+		p.expr(f.Cond, precOr)
+		p.print(" ? ")
+		p.expr(f.Cons, precAssign)
+		p.print(" : ")
+		p.expr(f.Alt, precAssign)
+
+	case *ast.LogicalExpr:
+		prec := logicalPrec[f.Op]
+		p.expr(f.Left, prec)
+		p.print(" " + f.Op.String() + " ")
+		p.expr(f.Right, prec+1)
+
+	case *ast.BinaryExpr:
+		prec := binaryPrec[f.Op]
+		if f.Op == ast.ExpBinaryOp {
+			// The exponentiation is right-associative and takes a unary
+			// expression on the right only
+			p.expr(f.Left, prec+1)
+			p.print(" " + f.Op.String() + " ")
+			p.expr(f.Right, precUnary)
+			break
+		}
+		p.expr(f.Left, prec)
+		p.print(" " + f.Op.String() + " ")
+		p.expr(f.Right, prec+1)
+
+	case *ast.UnaryExpr:
+		p.print(f.Op.String())
+		if needsSpace(f.Op, f.Arg) {
+			// Keep '- -x' and '- --x' from turning into '--x' and '---x'
+			p.print(" ")
+		}
+		p.expr(f.Arg, precUnary)
+
+	case *ast.UpdateExpr:
+		if f.Prefix {
+			p.print(f.Op.String())
+			p.expr(f.Arg, precMember)
+		} else {
+			p.expr(f.Arg, precMember)
+			p.print(f.Op.String())
+		}
+
+	case *ast.MemberExpr:
+		p.expr(f.Obj, precMember)
+		if f.Computed {
+			p.print("[")
+			p.expr(f.Prop, precLowest)
+			p.print("]")
+		} else {
+			p.print(".")
+			p.expr(f.Prop, precMember)
+		}
+
+	case *ast.CallExpr:
+		p.expr(f.Callee, precCall)
+		p.args(f.Args)
+
+	case *ast.NewExpr:
+		p.print("new ")
+		p.expr(f.Callee, precMember)
+		p.args(f.Args)
+
+	default:
+		p.error(n)
+	}
+}
+
+// objectLit prints the object literal on a single line if it fits in the
+// line width and has no methods, otherwise each property is put on its own
+// line.
+func (p *printer) objectLit(f *ast.ObjectLit) {
+	if len(f.Props) == 0 {
+		p.print("{}")
+		return
+	}
+
+	flat := &printer{Config: Config{TabWidth: p.TabWidth}}
+	for i, prop := range f.Props {
+		if prop.Fields.(*ast.Property).Kind == ast.MethodProperty {
+			flat = nil
+			break
+		}
+		if i > 0 {
+			flat.print(", ")
+		}
+		flat.property(prop.Fields.(*ast.Property))
+	}
+	if flat != nil && !bytes.ContainsRune(flat.buf.Bytes(), '\n') &&
+		(p.LineWidth <= 0 || p.column()+flat.buf.Len()+2 <= p.LineWidth) {
+		p.print("{")
+		p.buf.Write(flat.buf.Bytes())
+		p.print("}")
+		return
+	}
+
+	p.print("{")
+	p.indent++
+	for _, prop := range f.Props {
+		p.newline()
+		p.property(prop.Fields.(*ast.Property))
+		p.print(",")
+	}
+	p.indent--
+	p.newline()
+	p.print("}")
+}
+
+func (p *printer) property(f *ast.Property) {
+	switch {
+	case f.Computed:
+		p.print("[")
+		p.expr(f.Key, precAssign)
+		p.print("]")
+	case f.Kind == ast.ShorthandProperty:
+		p.expr(f.Value, precAssign)
+		return
+	default:
+		p.expr(f.Key, precAssign)
+	}
+
+	if f.Kind == ast.MethodProperty {
+		method := f.Value.Fields.(*ast.FuncDecl)
+		p.print("(")
+		p.exprList(method.Params)
+		p.print(") ")
+		p.stmt(method.Body)
+		return
+	}
+
+	p.print(": ")
+	p.expr(f.Value, precAssign)
+}
+
+func (p *printer) exprList(list []ast.Node) {
+	for i, e := range list {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expr(e, precAssign)
+	}
+}
+
+// args prints the arguments of a call. If they don't fit in the line width
+// each of them is put on its own line, unless only the body of the function
+// passed last doesn't.
+func (p *printer) args(list []ast.Node) {
+	p.print("(")
+	defer p.print(")")
+
+	if p.LineWidth <= 0 || len(list) == 0 {
+		p.exprList(list)
+		return
+	}
+
+	flat := &printer{Config: Config{TabWidth: p.TabWidth}}
+	flat.exprList(list)
+	firstLine := bytes.IndexByte(flat.buf.Bytes(), '\n')
+	if firstLine < 0 && p.column()+flat.buf.Len()+1 <= p.LineWidth {
+		p.buf.Write(flat.buf.Bytes())
+		return
+	}
+
+	if last := list[len(list)-1]; firstLine >= 0 && hasBlockBody(last) && p.column()+firstLine <= p.LineWidth {
+		head := &printer{Config: Config{TabWidth: p.TabWidth}}
+		head.exprList(list[:len(list)-1])
+		if !bytes.ContainsRune(head.buf.Bytes(), '\n') {
+			p.exprList(list)
+			return
+		}
+	}
+
+	p.indent++
+	for i, e := range list {
+		p.newline()
+		p.expr(e, precAssign)
+		if i < len(list)-1 {
+			p.print(",")
+		}
+	}
+	p.indent--
+	p.newline()
+}
+
+// hasBlockBody reports whether the node is a function with the body in
+// curly braces.
+func hasBlockBody(n ast.Node) bool {
+	switch f := n.Fields.(type) {
+	case *ast.FuncExpr:
+		return true
+	case *ast.ArrowFunc:
+		return f.Body.Type == ast.BlockStmtType
+	default:
+		return false
+	}
+}
+
+// startsStmt reports whether the expression would be taken for a statement
+// at the beginning of it, it starts with a '{' or 'def'.
+func startsStmt(n ast.Node) bool {
+	switch leftmost(n).Type {
+	case ast.ObjectLitType, ast.FuncExprType:
+		return true
+	default:
+		return false
+	}
+}
+
+// leftmost returns the expression the printed expression starts with.
+func leftmost(n ast.Node) ast.Node {
+	for {
+		switch f := n.Fields.(type) {
+		case *ast.SeqExpr:
+			n = f.Body[0]
+		case *ast.AssignExpr:
+			n = f.Left
+		case *ast.ConditionalExpr:
+			n = f.Cond
+		case *ast.LogicalExpr:
+			n = f.Left
+		case *ast.BinaryExpr:
+			n = f.Left
+		case *ast.MemberExpr:
+			n = f.Obj
+		case *ast.CallExpr:
+			n = f.Callee
+		case *ast.UpdateExpr:
+			if f.Prefix {
+				return n
+			}
+			n = f.Arg
+		default:
+			return n
+		}
+	}
+}
+
+func exprPrec(n ast.Node) int {
+	switch f := n.Fields.(type) {
+	case *ast.SeqExpr:
+		return precSeq
+	case *ast.AssignExpr, *ast.ArrowFunc:
+		return precAssign
+	case *ast.ConditionalExpr:
+		return precCond
+	case *ast.LogicalExpr:
+		return logicalPrec[f.Op]
+	case *ast.BinaryExpr:
+		return binaryPrec[f.Op]
+	case *ast.UnaryExpr:
+		return precUnary
+	case *ast.UpdateExpr:
+		return precUpdate
+	case *ast.CallExpr:
+		return precCall
+	default:
+		return precMember
+	}
+}
+
+// needsSpace reports whether the unary operator has to be separated from its
+// argument, so that the two don't read as a single token.
+func needsSpace(op ast.UnaryOp, arg ast.Node) bool {
+	if op == ast.TypeofUnaryOp {
+		return true
+	}
+	if op != ast.NegUnaryOp {
+		return false
+	}
+	switch f := arg.Fields.(type) {
+	case *ast.UnaryExpr:
+		return f.Op == ast.NegUnaryOp
+	case *ast.UpdateExpr:
+		return f.Prefix && f.Op == ast.DecUpdateOp
+	default:
+		return false
+	}
+}
+
+// numericLit returns the literal as it is written in the source code, the
+// literals made by the Builder may lack the raw text.
+func numericLit(n *ast.NumericLit) string {
+	switch {
+	case n.Raw != "":
+		return n.Raw
+	case n.Kind == ast.FloatNumeric:
+		return strconv.FormatFloat(n.Float, 'g', -1, 64)
+	default:
+		return strconv.FormatInt(n.Int, 10)
+	}
+}
+
+func (p *printer) print(s string) {
+	p.buf.WriteString(s)
+}
+
+// column returns the width of the last line of the output.
+func (p *printer) column() int {
+	line := p.buf.Bytes()[bytes.LastIndexByte(p.buf.Bytes(), '\n')+1:]
+	width := 0
+	for _, c := range line {
+		if c == '\t' {
+			width += p.TabWidth
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	for i := 0; i < p.indent; i++ {
+		p.buf.WriteByte('\t')
+	}
+}
+
+func (p *printer) error(n ast.Node) {
+	if p.err == nil {
+		p.err = &ErrUnexpectedNode{Node: n}
+	}
+}
@@ -0,0 +1,357 @@
+package printer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+func TestFprint(t *testing.T) {
+	type test struct {
+		in   string
+		want string
+	}
+
+	tests := []test{
+		{
+			in:   `42;"hello";  'single' ; true;false;null;;`,
+			want: "42;\n\"hello\";\n'single';\ntrue;\nfalse;\nnull;\n;\n",
+		}, {
+			in:   `0xff, 1_000, .5, 1E3;`,
+			want: "0xff, 1_000, .5, 1E3;\n",
+		}, {
+			in:   `let a,b=1,c=(d=2);`,
+			want: "let a, b = 1, c = d = 2;\n",
+		}, {
+			in:   `x = y = (1, 2);`,
+			want: "x = y = (1, 2);\n",
+		}, {
+			in:   `(a, b), c;`,
+			want: "(a, b), c;\n",
+		}, {
+			in:   `(1 + 2) * 3 - (4 - 5) / (6 * 7);`,
+			want: "(1 + 2) * 3 - (4 - 5) / (6 * 7);\n",
+		}, {
+			in:   `((1 + 2) + 3) + (4 + 5);`,
+			want: "1 + 2 + 3 + (4 + 5);\n",
+		}, {
+			in:   `(a == b) == (c < d + 1) == (e == f);`,
+			want: "a == b == c < d + 1 == (e == f);\n",
+		}, {
+			in:   `(a || b) && c || (d && e);`,
+			want: "(a || b) && c || d && e;\n",
+		}, {
+			in:   `-(-x) + !(!y) - -(a + b) - (-c).d;`,
+			want: "- -x + !!y - -(a + b) - (-c).d;\n",
+		}, {
+			in:   `typeof(x) === "number" !== (a instanceof B); !typeof -x; ("k" in o) < 1;`,
+			want: "typeof x === \"number\" !== a instanceof B;\n!typeof -x;\n\"k\" in o < 1;\n",
+		}, {
+			in:   `i++ + ++j - --k - -(--k); (++a) ** 2; (a.b)--; -~x;`,
+			want: "i++ + ++j - --k - - --k;\n++a ** 2;\na.b--;\n-~x;\n",
+		}, {
+			in:   `({}).a++; (def () {}).x--;`,
+			want: "({}.a++);\n(def () {}.x--);\n",
+		}, {
+			in:   `(a ** b) ** c ** (d ** e); (-a) ** -b; -(a ** b); a % (b * c);`,
+			want: "(a ** b) ** c ** d ** e;\n(-a) ** -b;\n-a ** b;\na % (b * c);\n",
+		}, {
+			in:   `(a | b) & c ^ (d & e) | f; (a << b) + c >> (d >>> ~e);`,
+			want: "(a | b) & c ^ d & e | f;\n(a << b) + c >> (d >>> ~e);\n",
+		}, {
+			in:   `x %= 2; x **= 2; x <<= 1; x >>>= 1; x &= (a | b);`,
+			want: "x %= 2;\nx **= 2;\nx <<= 1;\nx >>>= 1;\nx &= a | b;\n",
+		}, {
+			in:   `x += (a || b);`,
+			want: "x += a || b;\n",
+		}, {
+			in:   `(a + b).c[d, e](1, (2, 3))(4);`,
+			want: "(a + b).c[d, e](1, (2, 3))(4);\n",
+		}, {
+			in:   `[]; [1, (2, 3), [a = b]][0]; [1, 2,]; [, 1, , 2, ,]; [,];`,
+			want: "[];\n[1, (2, 3), [a = b]][0];\n[1, 2];\n[, 1, , 2, ,];\n[,];\n",
+		}, {
+			in:   `let o={a:1,"b c":x=2,3:[],[k+1]:{},y}; ({}).x; ({a:1}) + 1, 2; f({a, b: {c}});`,
+			want: "let o = {a: 1, \"b c\": x = 2, 3: [], [k + 1]: {}, y};\n({}.x);\n({a: 1} + 1, 2);\nf({a, b: {c}});\n",
+		}, {
+			in: `o = {m(a,b){return a;}, n: {p(){}}};`,
+			want: "o = {\n" +
+				"\tm(a, b) {\n" +
+				"\t\treturn a;\n" +
+				"\t},\n" +
+				"\tn: {\n" +
+				"\t\tp() {},\n" +
+				"\t},\n" +
+				"};\n",
+		}, {
+			in:   `let f = def(a,b){return a;}; (def fact(n) {})(5); (def () {}).x = 1;`,
+			want: "let f = def (a, b) {\n\treturn a;\n};\n(def fact(n) {}(5));\n(def () {}.x = 1);\n",
+		}, {
+			in:   `f(x => x + 1, () => {}, (a, b) => (c = a), b); x => y => ({x, y}); (x => x)(1); a + (x => x);`,
+			want: "f((x) => x + 1, () => {}, (a, b) => c = a, b);\n(x) => (y) => ({x, y});\n((x) => x)(1);\na + ((x) => x);\n",
+		}, {
+			in: `on("click", e => { e.stop(); }); g(def () { return; }, 1);`,
+			want: "on(\"click\", (e) => {\n" +
+				"\te.stop();\n" +
+				"});\n" +
+				"g(\n" +
+				"\tdef () {\n" +
+				"\t\treturn;\n" +
+				"\t},\n" +
+				"\t1\n" +
+				");\n",
+		}, {
+			in:   `a ? b : c ? d : e; (a ? b : c) ? d : e; x = a || b ? y = 1 : z = 2; (a ? b : c) + 1; ({} ? x => x : (y) => y);`,
+			want: "a ? b : c ? d : e;\n(a ? b : c) ? d : e;\nx = a || b ? y = 1 : z = 2;\n(a ? b : c) + 1;\n({} ? (x) => x : (y) => y);\n",
+		}, {
+			in:   `(f()).x; (f())(); new (a.b)(); (new A()).c; new (f())();`,
+			want: "(f()).x;\nf()();\nnew a.b();\nnew A().c;\nnew (f())();\n",
+		}, {
+			in:   `if(a)b;else if(c){d;}else e;`,
+			want: "if (a)\n\tb;\nelse if (c) {\n\td;\n} else\n\te;\n",
+		}, {
+			in:   `if (a) if (b) c; else d;`,
+			want: "if (a)\n\tif (b)\n\t\tc;\n\telse\n\t\td;\n",
+		}, {
+			in:   `while (x) { x -= 1; } while (y);`,
+			want: "while (x) {\n\tx -= 1;\n}\nwhile (y);\n",
+		}, {
+			in:   `do x; while (y); do {} while (z);`,
+			want: "do\n\tx;\nwhile (y);\ndo {} while (z);\n",
+		}, {
+			in:   `for (;;) {} for (let i = 0, j; i < 10; i += 1) x; for (i = 0, j = 1; ; ) ;`,
+			want: "for (;;) {}\nfor (let i = 0, j; i < 10; i += 1)\n\tx;\nfor (i = 0, j = 1;;);\n",
+		}, {
+			in:   `outer: for (;;) { inner: while (x) if (y) continue outer; else break; } a: b: { break a; }`,
+			want: "outer: for (;;) {\n\tinner: while (x)\n\t\tif (y)\n\t\t\tcontinue outer;\n\t\telse\n\t\t\tbreak;\n}\na: b: {\n\tbreak a;\n}\n",
+		}, {
+			in:   `try { throw 1; } catch (e) { f(e); } finally {} try {} finally { g(); }`,
+			want: "try {\n\tthrow 1;\n} catch (e) {\n\tf(e);\n} finally {}\ntry {} finally {\n\tg();\n}\n",
+		}, {
+			in:   `switch (x) { case 1: case 2: a; break; default: b; } switch (y) {}`,
+			want: "switch (x) {\n\tcase 1:\n\tcase 2:\n\t\ta;\n\t\tbreak;\n\tdefault:\n\t\tb;\n}\nswitch (y) {}\n",
+		}, {
+			in:   "switch (x) {\n\tcase 1:\n\t\ty;\n\t\tbreak;\n\tdefault:\n\t\tz;\n}\n",
+			want: "switch (x) {\n\tcase 1:\n\t\ty;\n\t\tbreak;\n\tdefault:\n\t\tz;\n}\n",
+		}, {
+			in:   `def f(a, b) { return; } def g() { return a, b; }`,
+			want: "def f(a, b) {\n\treturn;\n}\ndef g() {\n\treturn a, b;\n}\n",
+		}, {
+			in: `class A extends B { def constructor(x) { super(x); this.x = x; } def m() {} } class C {}`,
+			want: "class A extends B {\n" +
+				"\tdef constructor(x) {\n" +
+				"\t\tsuper(x);\n" +
+				"\t\tthis.x = x;\n" +
+				"\t}\n" +
+				"\n" +
+				"\tdef m() {}\n" +
+				"}\n" +
+				"class C {}\n",
+		}, {
+			in:   `{ { a; } {} }`,
+			want: "{\n\t{\n\t\ta;\n\t}\n\t{}\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got := sprint(t, mustParse(t, tc.in))
+			assert.Equal(t, tc.want, got)
+			testRoundTrip(t, tc.in)
+		})
+	}
+}
+
+func TestFprint_Builder(t *testing.T) {
+	type test struct {
+		name string
+		in   ast.Node
+		want string
+	}
+
+	b := ast.Builder{}
+	a, c, d := b.Identifier("a"), b.Identifier("c"), b.Identifier("d")
+
+	tests := []test{
+		{
+			name: "right associated binary",
+			in:   b.BinaryExpr(ast.SubBinaryOp, a, b.BinaryExpr(ast.AddBinaryOp, c, d)),
+			want: "a - (c + d)",
+		}, {
+			name: "left associated assign",
+			in:   b.AssignExpr(ast.SimpleAssignOp, b.MemberExpr(false, b.AssignExpr(ast.SimpleAssignOp, a, c), d), a),
+			want: "(a = c).d = a",
+		}, {
+			name: "unary of call",
+			in:   b.UnaryExpr(ast.NotUnaryOp, b.CallExpr(a, nil)),
+			want: "!a()",
+		}, {
+			name: "call of unary",
+			in:   b.CallExpr(b.UnaryExpr(ast.NotUnaryOp, a), []ast.Node{b.SeqExpr(c, d)}),
+			want: "(!a)((c, d))",
+		}, {
+			name: "escaped string",
+			in:   b.StringLit("a\"b\\\n\x00"),
+			want: `"a\"b\\\n\u0000"`,
+		}, {
+			name: "dangling else",
+			in: b.IfStmt(a,
+				b.IfStmt(c, b.ExprStmt(c), nil),
+				b.ExprStmt(d),
+			),
+			want: "if (a) {\n\tif (c)\n\t\tc;\n} else\n\td;",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sprint(t, tc.in))
+		})
+	}
+}
+
+func TestFprint_Comments(t *testing.T) {
+	type test struct {
+		in   string
+		want string
+	}
+
+	tests := []test{
+		{
+			in:   "// header\n\n\n\nlet a = 1;   // trailing\nlet b;\n\n/* footer */",
+			want: "// header\n\nlet a = 1; // trailing\nlet b;\n\n/* footer */\n",
+		}, {
+			in: "def f(a) { // opening\n" +
+				"// leading\n" +
+				"return a;\n" +
+				"// closing\n" +
+				"}",
+			want: "def f(a) { // opening\n" +
+				"\t// leading\n" +
+				"\treturn a;\n" +
+				"\t// closing\n" +
+				"}\n",
+		}, {
+			in: "class A {\n" +
+				"\t// first\n" +
+				"\tdef a() {}\n" +
+				"\t// second\n" +
+				"\tdef b() {} }",
+			want: "class A {\n" +
+				"\t// first\n" +
+				"\tdef a() {}\n" +
+				"\n" +
+				"\t// second\n" +
+				"\tdef b() {}\n" +
+				"}\n",
+		}, {
+			in:   "if (a) x; /* a */ else /* b */ { y; }",
+			want: "if (a)\n\tx;\nelse {\n\t/* a */\n\t/* b */\n\ty;\n}\n",
+		}, {
+			in:   "f(a, // first\n b);\n{}",
+			want: "f(a, b); // first\n{}\n",
+		}, {
+			in:   "f(a, // after a\n b /* before paren */);",
+			want: "f(a, b); /* before paren */ // after a\n",
+		}, {
+			in:   "f(a, // one\n b, // two\n c);\nx;",
+			want: "f(a, b, c); // one\n// two\nx;\n",
+		}, {
+			in:   "{ /* empty */ }",
+			want: "{ /* empty */\n}\n",
+		}, {
+			in:   "switch (x) {\ncase 1: a; // one\n// two\ncase 2:\n}",
+			want: "switch (x) {\n\tcase 1:\n\t\ta; // one\n\t// two\n\tcase 2:\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			p := parser.NewParser(tokenizer.NewScanner(tc.in), ast.Builder{})
+			p.KeepComments()
+			node, err := p.Parse()
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, sprint(t, node))
+		})
+	}
+}
+
+func TestConfig_Fprint_LineWidth(t *testing.T) {
+	in := `result = compute(first, second(third, fourth), new Fifth(sixth, seventh), eighth);`
+	want := "result = compute(\n" +
+		"\tfirst,\n" +
+		"\tsecond(third, fourth),\n" +
+		"\tnew Fifth(sixth, seventh),\n" +
+		"\teighth\n" +
+		");\n"
+
+	cfg := Config{LineWidth: 40, TabWidth: 4}
+	var out strings.Builder
+	require.NoError(t, cfg.Fprint(&out, mustParse(t, in)))
+	assert.Equal(t, want, out.String())
+	testRoundTrip(t, out.String())
+
+	out.Reset()
+	require.NoError(t, cfg.Fprint(&out, mustParse(t, `point = {x: first, y: second, z: third, w: 1};`)))
+	assert.Equal(t, "point = {\n\tx: first,\n\ty: second,\n\tz: third,\n\tw: 1,\n};\n", out.String())
+	testRoundTrip(t, out.String())
+
+	out.Reset()
+	cfg = Config{LineWidth: 0}
+	require.NoError(t, cfg.Fprint(&out, mustParse(t, in)))
+	assert.Equal(t, in+"\n", out.String())
+}
+
+func TestFprint_Error(t *testing.T) {
+	b := ast.Builder{}
+	var out strings.Builder
+	err := Fprint(&out, b.Program(b.ExprStmt(b.NumericLit(1)), b.BadStmt()))
+	assert.EqualError(t, err, "can't print node BadStmtType")
+	assert.Empty(t, out.String())
+}
+
+// testRoundTrip checks that printing the parsed program gives the source
+// which parses to the same tree and is printed the same way again.
+func testRoundTrip(t *testing.T, in string) {
+	t.Helper()
+
+	want := mustParse(t, in)
+	printed := sprint(t, want)
+	got := mustParse(t, printed)
+
+	clearLoc(want)
+	clearLoc(got)
+	assert.Exactly(t, want, got)
+	assert.Equal(t, printed, sprint(t, got))
+}
+
+func sprint(t *testing.T, node ast.Node) string {
+	t.Helper()
+	var out strings.Builder
+	require.NoError(t, Fprint(&out, node))
+	return out.String()
+}
+
+func mustParse(t *testing.T, in string) ast.Node {
+	t.Helper()
+	p := parser.NewParser(tokenizer.NewScanner(in), ast.Builder{})
+	node, err := p.Parse()
+	require.NoError(t, err, in)
+	return node
+}
+
+func clearLoc(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			n.Loc = ast.Loc{}
+		}
+		return true
+	})
+}
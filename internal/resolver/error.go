@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+type ErrUndeclared struct {
+	Name string
+}
+
+func (e *ErrUndeclared) Error() string {
+	return fmt.Sprintf("undeclared name: %s", e.Name)
+}
+
+type ErrRedeclared struct {
+	Name string
+	Prev ast.Position
+}
+
+func (e *ErrRedeclared) Error() string {
+	return fmt.Sprintf("%s redeclared in this scope, previous declaration at %d:%d",
+		e.Name, e.Prev.Line, e.Prev.Column)
+}
+
+type ErrShadowed struct {
+	Name  string
+	Outer ast.Position
+}
+
+func (e *ErrShadowed) Error() string {
+	return fmt.Sprintf("declaration of %s shadows declaration at %d:%d",
+		e.Name, e.Outer.Line, e.Outer.Column)
+}
+
+// Error is a problem found by the resolver at the location. Warnings point
+// to a suspicious code which is still valid.
+type Error struct {
+	Loc     ast.Loc
+	Err     error
+	Warning bool
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Loc.Start.Line, e.Loc.Start.Column, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList is the list of the problems in the source order.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to this error list, it is nil if there
+// are no errors. Warnings are left out.
+func (l ErrorList) Err() error {
+	var errs ErrorList
+	for _, e := range l {
+		if !e.Warning {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (l ErrorList) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Loc.Start.Offset < l[j].Loc.Start.Offset
+	})
+}
@@ -0,0 +1,282 @@
+package resolver
+
+import (
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+// Info is the result of resolving a program.
+type Info struct {
+	// Scopes maps the Program, BlockStmt, ForStmt, CatchClause, SwitchCase
+	// and the function nodes to the scopes they open
+	Scopes map[ast.Node]*Scope
+	// Defs maps the declaring identifiers to the objects they declare
+	Defs map[ast.Node]*Object
+	// Uses maps the identifiers to the objects they refer to, the
+	// undeclared identifiers are left out
+	Uses map[ast.Node]*Object
+}
+
+// ObjectOf returns the object the identifier declares or refers to, or nil
+// if there is none.
+func (info *Info) ObjectOf(ident ast.Node) *Object {
+	if obj, ok := info.Defs[ident]; ok {
+		return obj
+	}
+	return info.Uses[ident]
+}
+
+// Resolve links the identifiers of the program to their declarations. The
+// function and class declarations are visible in the whole scope they are
+// declared in, the variables only after their declaration. The bodies of the
+// functions are resolved at the end of the scope the function is declared
+// in, so that they can refer to everything declared in it.
+//
+// The list contains the undeclared identifiers and the names declared twice
+// in the same scope. Declarations shadowing the ones from the enclosing
+// scopes are reported as warnings.
+func Resolve(program ast.Node) (*Info, ErrorList) {
+	r := &resolver{
+		info: &Info{
+			Scopes: map[ast.Node]*Scope{},
+			Defs:   map[ast.Node]*Object{},
+			Uses:   map[ast.Node]*Object{},
+		},
+		scope:   Universe,
+		delayed: map[*Scope][]ast.Node{},
+	}
+
+	r.block(program, program.Fields.(*ast.Program).Body)
+	r.errors.sort()
+
+	return r.info, r.errors
+}
+
+type resolver struct {
+	info   *Info
+	scope  *Scope
+	errors ErrorList
+
+	// delayed are the functions to resolve at the end of the scope
+	delayed map[*Scope][]ast.Node
+}
+
+func (r *resolver) node(n ast.Node) {
+	if n == nil {
+		return
+	}
+
+	switch f := n.Fields.(type) {
+	case *ast.BlockStmt:
+		r.block(n, f.Body)
+
+	case *ast.ForStmt:
+		r.openScope(n)
+		r.children(n)
+		r.closeScope()
+
+	case *ast.LabeledStmt:
+		// The labels are neither declarations nor uses of the names
+		r.node(f.Body)
+
+	case *ast.BreakStmt, *ast.ContinueStmt:
+		// nothing to do
+
+	case *ast.CatchClause:
+		// The parameter shares the scope with the body as the function
+		// parameters do
+		r.openScope(n)
+		r.declare(&Object{Kind: ParamObj, Ident: f.Param})
+		r.stmtList(f.Body.Fields.(*ast.BlockStmt).Body)
+		r.closeScope()
+
+	case *ast.SwitchCase:
+		// The falling through cases don't share their variables
+		r.node(f.Test)
+		r.block(n, f.Body)
+
+	case *ast.VarDecl:
+		r.node(f.Init)
+		r.declare(&Object{Kind: VarObj, Ident: f.ID, Decl: n})
+
+	case *ast.FuncDecl:
+		if _, ok := r.info.Defs[f.Name]; !ok {
+			// Not at the block level, hence not declared in advance
+			r.declare(&Object{Kind: FuncObj, Ident: f.Name, Decl: n})
+		}
+		r.delay(n)
+
+	case *ast.FuncExpr, *ast.ArrowFunc:
+		r.delay(n)
+
+	case *ast.ClassDecl:
+		if _, ok := r.info.Defs[f.ID]; !ok {
+			r.declare(&Object{Kind: ClassObj, Ident: f.ID, Decl: n})
+		}
+		r.node(f.Super)
+		for _, member := range f.Body.Fields.(*ast.BlockStmt).Body {
+			if method, ok := member.Fields.(*ast.FuncDecl); ok {
+				r.info.Defs[method.Name] = &Object{
+					Kind:  MethodObj,
+					Name:  identName(method.Name),
+					Ident: method.Name,
+					Decl:  member,
+				}
+				r.delay(member)
+			} else {
+				r.node(member)
+			}
+		}
+
+	case *ast.Property:
+		if f.Computed {
+			r.node(f.Key)
+		}
+		if f.Kind == ast.MethodProperty {
+			method := f.Value.Fields.(*ast.FuncDecl)
+			r.info.Defs[method.Name] = &Object{
+				Kind:  MethodObj,
+				Name:  identName(method.Name),
+				Ident: method.Name,
+				Decl:  f.Value,
+			}
+			r.delay(f.Value)
+		} else {
+			r.node(f.Value)
+		}
+
+	case *ast.MemberExpr:
+		r.node(f.Obj)
+		if f.Computed {
+			r.node(f.Prop)
+		}
+
+	case *ast.Identifier:
+		r.use(n)
+
+	default:
+		r.children(n)
+	}
+}
+
+// block resolves the statements in a new scope. The functions and classes
+// are declared first, the bodies of the functions are resolved last.
+func (r *resolver) block(n ast.Node, body []ast.Node) {
+	r.openScope(n)
+	r.stmtList(body)
+	r.closeScope()
+}
+
+func (r *resolver) stmtList(body []ast.Node) {
+	for _, stmt := range body {
+		switch f := stmt.Fields.(type) {
+		case *ast.FuncDecl:
+			r.declare(&Object{Kind: FuncObj, Ident: f.Name, Decl: stmt})
+		case *ast.ClassDecl:
+			r.declare(&Object{Kind: ClassObj, Ident: f.ID, Decl: stmt})
+		}
+	}
+
+	for _, stmt := range body {
+		r.node(stmt)
+	}
+}
+
+// delay schedules resolving of the function body to the end of the current
+// scope.
+func (r *resolver) delay(n ast.Node) {
+	r.delayed[r.scope] = append(r.delayed[r.scope], n)
+}
+
+// function resolves the function body in the scope with its parameters.
+// The name of the function expression is declared in the same scope as it
+// is visible only inside of the function.
+func (r *resolver) function(n ast.Node) {
+	var params []ast.Node
+	var body ast.Node
+
+	r.openScope(n)
+	switch f := n.Fields.(type) {
+	case *ast.FuncDecl:
+		params, body = f.Params, f.Body
+	case *ast.FuncExpr:
+		if f.Name != nil {
+			r.declare(&Object{Kind: FuncObj, Ident: f.Name, Decl: n})
+		}
+		params, body = f.Params, f.Body
+	case *ast.ArrowFunc:
+		params, body = f.Params, f.Body
+	}
+	for _, param := range params {
+		r.declare(&Object{Kind: ParamObj, Ident: param})
+	}
+
+	if block, ok := body.Fields.(*ast.BlockStmt); ok {
+		r.stmtList(block.Body)
+	} else {
+		r.node(body)
+	}
+	r.closeScope()
+}
+
+// children resolves the children of the node.
+func (r *resolver) children(n ast.Node) {
+	ast.Inspect(n, func(c ast.Node) bool {
+		if c == n {
+			return true
+		}
+		r.node(c)
+		return false
+	})
+}
+
+func (r *resolver) openScope(n ast.Node) {
+	r.scope = NewScope(r.scope, n)
+	r.info.Scopes[n] = r.scope
+}
+
+// closeScope resolves the delayed function bodies of the scope and returns
+// to the parent scope.
+func (r *resolver) closeScope() {
+	// Resolving a function may delay more of them
+	for i := 0; i < len(r.delayed[r.scope]); i++ {
+		r.function(r.delayed[r.scope][i])
+	}
+	delete(r.delayed, r.scope)
+
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) declare(obj *Object) {
+	obj.Name = identName(obj.Ident)
+	r.info.Defs[obj.Ident] = obj
+
+	if prev := r.scope.Insert(obj); prev != nil {
+		r.report(obj.Ident, false, &ErrRedeclared{Name: obj.Name, Prev: prev.Ident.Loc.Start})
+		return
+	}
+
+	if outer := r.scope.Parent.Lookup(obj.Name); outer != nil && outer.Kind != BuiltinObj {
+		r.report(obj.Ident, true, &ErrShadowed{Name: obj.Name, Outer: outer.Ident.Loc.Start})
+	}
+}
+
+func (r *resolver) use(ident ast.Node) {
+	name := identName(ident)
+	obj := r.scope.Lookup(name)
+	if obj == nil {
+		r.report(ident, false, &ErrUndeclared{Name: name})
+		return
+	}
+	r.info.Uses[ident] = obj
+}
+
+func (r *resolver) report(n ast.Node, warning bool, err error) {
+	r.errors = append(r.errors, &Error{Loc: n.Loc, Err: err, Warning: warning})
+}
+
+func identName(n ast.Node) string {
+	if id, ok := n.Fields.(*ast.Identifier); ok {
+		return id.Name
+	}
+	return ""
+}
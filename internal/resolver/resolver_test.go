@@ -0,0 +1,205 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+func TestResolve(t *testing.T) {
+	type test struct {
+		in string
+		// wantUses are the identifier uses as "name@line:col -> kind@line:col"
+		wantUses []string
+	}
+
+	tests := []test{
+		{
+			in:       `let x = 1; x;`,
+			wantUses: []string{"x@1:12 -> variable@1:5"},
+		}, {
+			in:       `let x = 1; { let x = x; x; }`,
+			wantUses: []string{"x@1:22 -> variable@1:5", "x@1:25 -> variable@1:18"},
+		}, {
+			in:       `f(); def f(a) { return a + g(); } def g() { return x; } let x;`,
+			wantUses: []string{"f@1:1 -> function@1:10", "a@1:24 -> parameter@1:12", "g@1:28 -> function@1:39", "x@1:52 -> variable@1:61"},
+		}, {
+			in:       `for (let i = 0; i < 10; i += 1) { print(i); }`,
+			wantUses: []string{"i@1:17 -> variable@1:10", "i@1:25 -> variable@1:10", "print@1:35 -> builtin", "i@1:41 -> variable@1:10"},
+		}, {
+			in: `class A {} class B extends A { def m(a) { return this.a + a + super(a); } } new B().m;`,
+			wantUses: []string{
+				"A@1:28 -> class@1:7", "a@1:59 -> parameter@1:38", "a@1:69 -> parameter@1:38",
+				"B@1:81 -> class@1:18",
+			},
+		}, {
+			in:       `def f() { def g() { return h(); } def h() {} return g(); }`,
+			wantUses: []string{"h@1:28 -> function@1:39", "g@1:53 -> function@1:15"},
+		}, {
+			in:       `let o; o.x = o[x]; let x;`,
+			wantUses: []string{"o@1:8 -> variable@1:5", "o@1:14 -> variable@1:5"},
+		}, {
+			in:       `let a = 1, b = [a, , [a]];`,
+			wantUses: []string{"a@1:17 -> variable@1:5", "a@1:23 -> variable@1:5"},
+		}, {
+			in:       `let a, b, o = {a, b: a, [b]: 1, "c": 2, m(b) { return a + b; }};`,
+			wantUses: []string{"a@1:16 -> variable@1:5", "a@1:22 -> variable@1:5", "b@1:26 -> variable@1:8", "a@1:55 -> variable@1:5", "b@1:59 -> parameter@1:43"},
+		}, {
+			in:       `let f = def g(n) { return g(n) + y; }, y = (x) => x => x + y;`,
+			wantUses: []string{"g@1:27 -> function@1:13", "n@1:29 -> parameter@1:15", "y@1:34 -> variable@1:40", "x@1:56 -> parameter@1:51", "y@1:60 -> variable@1:40"},
+		}, {
+			in:       `let x; x: while (x) { continue x; }`,
+			wantUses: []string{"x@1:18 -> variable@1:5"},
+		}, {
+			in:       `let e; try { throw e; } catch (e) { e; } finally { e; }`,
+			wantUses: []string{"e@1:20 -> variable@1:5", "e@1:37 -> parameter@1:32", "e@1:52 -> variable@1:5"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			program := mustParse(t, tc.in)
+			info, _ := Resolve(program)
+
+			var gotUses []string
+			for ident, obj := range info.Uses {
+				gotUses = append(gotUses, formatUse(ident, obj))
+			}
+			sortByPos(gotUses)
+			assert.Equal(t, tc.wantUses, gotUses)
+		})
+	}
+}
+
+func TestResolve_Error(t *testing.T) {
+	type test struct {
+		in       string
+		wantErrs []string
+	}
+
+	tests := []test{
+		{
+			in:       `x; let x;`,
+			wantErrs: []string{"1:1: undeclared name: x"},
+		}, {
+			in:       `let o; o.x = o[x]; let x;`,
+			wantErrs: []string{"1:16: undeclared name: x"},
+		}, {
+			in: `let x; let x; def x() {}`,
+			wantErrs: []string{
+				"1:5: x redeclared in this scope, previous declaration at 1:19",
+				"1:12: x redeclared in this scope, previous declaration at 1:19",
+			},
+		}, {
+			in:       `def f(a, a) { let a; }`,
+			wantErrs: []string{"1:10: a redeclared in this scope, previous declaration at 1:7", "1:19: a redeclared in this scope, previous declaration at 1:7"},
+		}, {
+			in:       `let x; def f(x) { { let x; } }`,
+			wantErrs: []string{"warning: 1:14: declaration of x shadows declaration at 1:5", "warning: 1:25: declaration of x shadows declaration at 1:14"},
+		}, {
+			in:       `def print() {} for (let i;;) { let i; }`,
+			wantErrs: []string{"warning: 1:36: declaration of i shadows declaration at 1:25"},
+		}, {
+			in:       `def f() { return g(); } { def g() {} }`,
+			wantErrs: []string{"1:18: undeclared name: g"},
+		}, {
+			in:       `try {} catch (e) { let e; }`,
+			wantErrs: []string{"1:24: e redeclared in this scope, previous declaration at 1:15"},
+		}, {
+			in:       `let x; switch (x) { case 1: let y = x; case y: y; }`,
+			wantErrs: []string{"1:45: undeclared name: y", "1:48: undeclared name: y"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			_, errs := Resolve(mustParse(t, tc.in))
+
+			var gotErrs []string
+			for _, err := range errs {
+				if err.Warning {
+					gotErrs = append(gotErrs, "warning: "+err.Error())
+				} else {
+					gotErrs = append(gotErrs, err.Error())
+				}
+			}
+			assert.Equal(t, tc.wantErrs, gotErrs)
+		})
+	}
+}
+
+func TestResolve_Scopes(t *testing.T) {
+	program := mustParse(t, `let a; def f(b) { let c; { let d; } } for (let e;;) {}`)
+	info, errs := Resolve(program)
+	require.NoError(t, errs.Err())
+
+	global := info.Scopes[program]
+	require.NotNil(t, global)
+	assert.Same(t, Universe, global.Parent)
+	assert.Equal(t, []string{"a", "f"}, scopeNames(global))
+
+	// The function bodies are resolved at the end of the enclosing scope
+	require.Len(t, global.Children, 2)
+	forScope, funcScope := global.Children[0], global.Children[1]
+
+	assert.Equal(t, ast.ForStmtType, forScope.Node.Type)
+	assert.Equal(t, []string{"e"}, scopeNames(forScope))
+	require.Len(t, forScope.Children, 1)
+	assert.Equal(t, ast.BlockStmtType, forScope.Children[0].Node.Type)
+
+	assert.Equal(t, ast.FuncDeclType, funcScope.Node.Type)
+	assert.Equal(t, []string{"b", "c"}, scopeNames(funcScope))
+	require.Len(t, funcScope.Children, 1)
+	assert.Equal(t, []string{"d"}, scopeNames(funcScope.Children[0]))
+
+	assert.Equal(t, FuncObj, global.Lookup("f").Kind)
+	assert.Nil(t, global.Lookup("b"))
+	assert.Equal(t, BuiltinObj, global.Lookup("print").Kind)
+}
+
+func formatUse(ident ast.Node, obj *Object) string {
+	use := fmt.Sprintf("%s@%d:%d -> %s", obj.Name, ident.Loc.Start.Line, ident.Loc.Start.Column, obj.Kind)
+	if obj.Ident != nil {
+		use += fmt.Sprintf("@%d:%d", obj.Ident.Loc.Start.Line, obj.Ident.Loc.Start.Column)
+	}
+	return use
+}
+
+// sortByPos sorts the formatted uses by the position of the identifier.
+func sortByPos(uses []string) {
+	pos := func(s string) (line, col int) {
+		s = s[strings.Index(s, "@")+1:]
+		_, _ = fmt.Sscanf(s, "%d:%d", &line, &col)
+		return line, col
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		li, ci := pos(uses[i])
+		lj, cj := pos(uses[j])
+		return li < lj || li == lj && ci < cj
+	})
+}
+
+func scopeNames(s *Scope) []string {
+	var names []string
+	for name := range s.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mustParse(t *testing.T, in string) ast.Node {
+	t.Helper()
+	p := parser.NewParser(tokenizer.NewScanner(in), ast.Builder{})
+	node, err := p.Parse()
+	require.NoError(t, err)
+	return node
+}
@@ -0,0 +1,104 @@
+package resolver
+
+import (
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+type ObjKind int
+
+const (
+	BadObj ObjKind = iota
+	VarObj
+	ParamObj
+	FuncObj
+	MethodObj
+	ClassObj
+	BuiltinObj
+)
+
+var objKindNames = [...]string{
+	"bad",
+	"variable",
+	"parameter",
+	"function",
+	"method",
+	"class",
+	"builtin",
+}
+
+func (k ObjKind) String() string {
+	if k >= 0 && int(k) < len(objKindNames) {
+		return objKindNames[k]
+	}
+	return objKindNames[BadObj]
+}
+
+// Object is a named entity of the program: a variable, a parameter, a
+// function, a method, a class or a builtin.
+type Object struct {
+	Kind ObjKind
+	Name string
+	// Ident is the declaring Identifier, it is nil for builtins
+	Ident ast.Node
+	// Decl is the VarDecl, FuncDecl, FuncExpr or ClassDecl declaring the
+	// object, it is nil for parameters and builtins
+	Decl ast.Node
+	// Scope is the scope the object is declared in, it is nil for methods
+	// as they are not visible by name
+	Scope *Scope
+}
+
+// Scope maps the names to the objects declared in a Program, BlockStmt,
+// function or the init clause of a ForStmt.
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+	// Node is the node opening the scope, it is nil for the Universe
+	Node    ast.Node
+	Objects map[string]*Object
+}
+
+func NewScope(parent *Scope, node ast.Node) *Scope {
+	s := &Scope{
+		Parent:  parent,
+		Node:    node,
+		Objects: map[string]*Object{},
+	}
+	// The Universe is shared by all the programs, it doesn't keep track
+	// of its children
+	if parent != nil && parent != Universe {
+		parent.Children = append(parent.Children, s)
+	}
+	return s
+}
+
+// Lookup returns the object with the name declared in the scope or one of
+// its parents, or nil if there is none.
+func (s *Scope) Lookup(name string) *Object {
+	for ; s != nil; s = s.Parent {
+		if obj, ok := s.Objects[name]; ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Insert adds the object to the scope unless there is already an object with
+// the same name in it, in which case the existing object is returned.
+func (s *Scope) Insert(obj *Object) *Object {
+	if prev, ok := s.Objects[obj.Name]; ok {
+		return prev
+	}
+	s.Objects[obj.Name] = obj
+	obj.Scope = s
+	return nil
+}
+
+// Universe is the scope of the builtins, it is the parent of every program
+// scope.
+var Universe *Scope
+
+func init() {
+	Universe = NewScope(nil, nil)
+	Universe.Insert(&Object{Kind: BuiltinObj, Name: "print"})
+}
@@ -3,10 +3,19 @@ package tokenizer
 import "fmt"
 
 type ErrUnexpectedToken struct {
-	Position   int
+	Position   Position
 	CodeString string
 }
 
 func (u *ErrUnexpectedToken) Error() string {
-	return fmt.Sprintf("unexpected token at position %d: \"%s\"", u.Position, u.CodeString)
+	return fmt.Sprintf("unexpected character %q", u.CodeString)
+}
+
+type ErrUnterminatedString struct {
+	Position   Position
+	CodeString string
+}
+
+func (u *ErrUnterminatedString) Error() string {
+	return "unterminated string literal"
 }
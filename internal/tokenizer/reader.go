@@ -0,0 +1,188 @@
+package tokenizer
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// readChunk is the minimal number of bytes ReaderScanner reads at once.
+const readChunk = 4096
+
+// maxLookahead is the number of bytes after a token the scanner may look at
+// to tell where the token ends, e.g. "1e+5" vs "1e+".
+const maxLookahead = 4
+
+// ReaderScanner produces the same tokens as the Scanner reading the source
+// code from the io.Reader. It keeps in memory only the part of the input
+// with the current token, the buffer grows beyond a few kilobytes only for
+// the tokens longer than that.
+//
+// A read error is returned once as the error of NextToken after the tokens
+// buffered before it, the input is considered to end there.
+type ReaderScanner struct {
+	r io.Reader
+	// window is the buffered input, cursor is the offset in it and base is
+	// the offset of the window in the input
+	window string
+	cursor int
+	base   int
+	eof    bool
+	// err is the read error to return once the window is consumed
+	err error
+	buf []byte
+
+	line      int
+	lineStart int
+}
+
+func NewReaderScanner(r io.Reader) *ReaderScanner {
+	return &ReaderScanner{
+		r:    r,
+		line: 1,
+	}
+}
+
+func (s *ReaderScanner) NextToken() (Token, error) {
+	s.skipSpace()
+
+	start := s.pos()
+	if s.cursor >= len(s.window) {
+		if err := s.err; err != nil {
+			s.err = nil
+			return Token{}, err
+		}
+		return Token{Type: EOF, Start: start, End: start}, nil
+	}
+
+	typ, n := scanToken(s.window[s.cursor:])
+	for !s.eof && !complete(s.window[s.cursor:], typ, n) {
+		s.fill()
+		typ, n = scanToken(s.window[s.cursor:])
+	}
+
+	rest := s.window[s.cursor:]
+	if n == 0 {
+		n, err := scanError(rest, start)
+		err = cloneCodeString(err)
+		s.advance(n)
+		return Token{}, err
+	}
+	s.advance(n)
+
+	// The keywords and the punctuation are spelled as their types
+	value := string(typ)
+	if value != rest[:n] {
+		value = cloneString(rest[:n])
+	}
+
+	return Token{
+		Type:  typ,
+		Value: value,
+		Start: start,
+		End:   s.pos(),
+	}, nil
+}
+
+// complete reports whether the token scanned from the buffered input would
+// be the same if there was more of it.
+func complete(rest string, typ TokenType, n int) bool {
+	switch {
+	case n == 0 && (rest[0] == '"' || rest[0] == '\''):
+		// The unterminated string spans to the end of the line
+		return strings.IndexByte(rest, '\n') >= 0
+	case typ == MultiplicativeOp && strings.HasPrefix(rest, "/*"):
+		// The block comment isn't terminated in the buffered input
+		return false
+	default:
+		return n+maxLookahead < len(rest)
+	}
+}
+
+// skipSpace moves the cursor past the whitespace reading more of the input
+// as needed.
+func (s *ReaderScanner) skipSpace() {
+	for {
+		for s.cursor < len(s.window) {
+			switch s.window[s.cursor] {
+			case '\n':
+				s.line++
+				s.lineStart = s.base + s.cursor + 1
+			case ' ', '\t', '\r', '\f':
+			default:
+				return
+			}
+			s.cursor++
+		}
+
+		if s.eof {
+			return
+		}
+		s.fill()
+	}
+}
+
+// fill drops the consumed part of the window and appends at least one
+// chunk of the input to it, or as much as there is left unconsumed so that
+// the long tokens take linear time to read. A read error ends the input,
+// it is kept to be returned after the buffered tokens.
+func (s *ReaderScanner) fill() {
+	size := readChunk
+	if rest := len(s.window) - s.cursor; rest > size {
+		size = rest
+	}
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+
+	n, err := io.ReadAtLeast(s.r, s.buf[:size], 1)
+	s.base += s.cursor
+	s.window = s.window[s.cursor:] + string(s.buf[:n])
+	s.cursor = 0
+
+	if err != nil {
+		s.eof = true
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+	}
+}
+
+// advance moves the cursor n bytes forward keeping track of line breaks.
+func (s *ReaderScanner) advance(n int) {
+	for i := s.cursor; i < s.cursor+n; i++ {
+		if s.window[i] == '\n' {
+			s.line++
+			s.lineStart = s.base + i + 1
+		}
+	}
+	s.cursor += n
+}
+
+func (s *ReaderScanner) pos() Position {
+	offset := s.base + s.cursor
+	return Position{
+		Offset: offset,
+		Line:   s.line,
+		Column: offset - s.lineStart + 1,
+	}
+}
+
+// cloneString copies the string so that it doesn't keep the window it is
+// sliced from in memory.
+func cloneString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	sb.WriteString(s)
+	return sb.String()
+}
+
+func cloneCodeString(err error) error {
+	switch err := err.(type) {
+	case *ErrUnexpectedToken:
+		err.CodeString = cloneString(err.CodeString)
+	case *ErrUnterminatedString:
+		err.CodeString = cloneString(err.CodeString)
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package tokenizer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderScanner_NextToken(t *testing.T) {
+	tests := []string{
+		``,
+		`let x = 42; def f(a, b) { return a.b[c]; }`,
+		`let o = {a: 1, "b": [2], [c]: d, e, f() {}};`,
+		`f(x => x, (a, b) => { return a; }, def (c) {}); a ==> b = > c =>`,
+		`a ? b : c ? d : e; ?? ?: x?y:z`,
+		`outer: while (x) { break; continue outer; } breaks continued`,
+		`try { throw e; } catch (e) {} finally {} trying thrown`,
+		`switch (x) { case 1: break; default: } switches cases defaults`,
+		`a % b ** c << d >> e >>> f & g | h ^ ~i && j || k`,
+		`a %= 1; a **= 2; a <<= 3; a >>= 4; a >>>= 5; a &= 6; a |= 7; a ^= 8; a>>>=b`,
+		`++i; i++; --i; i--; a+++b a---b a+-+b a-- -b ++=`,
+		`a === b !== c ==== d typeof x instanceof C "k" in o typeofx inside instanceOf`,
+		`0xFF 0x 1_000 1_ 3.14 .5 1. 1.e5 1e9 2.5E-3 1e+ 1e a.5 1abc`,
+		`"hello" 'it\'s' "a\"b" "\\" "ü" "a\q"`,
+		"\"unterminated\nlet x;\n'also",
+		"// line comment\n/* block\ncomment */ x /**/ y /*/ z */ / * /= //",
+		`/* unterminated`,
+		"a @ b # ü & | c",
+		"x\n\n  y\n\tz",
+		"/* " + strings.Repeat("long comment ", 1000) + "*/ " + strings.Repeat("ident", 1000),
+		benchmarkSource(10),
+	}
+
+	for _, in := range tests {
+		name := in
+		if len(name) > 50 {
+			name = name[:50]
+		}
+		t.Run(name, func(t *testing.T) {
+			want := tokenize(NewScanner(in))
+			assert.Equal(t, want, tokenize(NewReaderScanner(strings.NewReader(in))))
+			assert.Equal(t, want, tokenize(NewReaderScanner(iotest.OneByteReader(strings.NewReader(in)))))
+			assert.Equal(t, want, tokenize(NewReaderScanner(iotest.DataErrReader(strings.NewReader(in)))))
+		})
+	}
+}
+
+func TestReaderScanner_NextToken_Bounded(t *testing.T) {
+	const n = 300000
+	s := NewReaderScanner(&repeatReader{s: "let x = 1; // comment\n", n: n})
+
+	lets, maxWindow := 0, 0
+	for {
+		tok, err := s.NextToken()
+		require.NoError(t, err)
+		if tok.Type == EOF {
+			assert.Equal(t, n+1, tok.Start.Line)
+			break
+		}
+		if tok.Type == LetKeyword {
+			lets++
+		}
+		if len(s.window) > maxWindow {
+			maxWindow = len(s.window)
+		}
+	}
+
+	assert.Equal(t, n, lets)
+	assert.LessOrEqual(t, maxWindow, 2*readChunk)
+}
+
+func TestReaderScanner_NextToken_ReadError(t *testing.T) {
+	readErr := errors.New("read error")
+	s := NewReaderScanner(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(readErr)))
+
+	// The tokens buffered before the error come first
+	tok, err := s.NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, LetKeyword, tok.Type)
+
+	tok, err = s.NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, Token{Type: Identifier, Value: "x", Start: Position{4, 1, 5}, End: Position{5, 1, 6}}, tok)
+
+	_, err = s.NextToken()
+	assert.Equal(t, readErr, err)
+
+	tok, err = s.NextToken()
+	require.NoError(t, err)
+	assert.Equal(t, EOF, tok.Type)
+}
+
+func BenchmarkReaderScanner(b *testing.B) {
+	benchmarkNextToken(b, func(in string) nextTokener {
+		return NewReaderScanner(strings.NewReader(in))
+	})
+}
+
+// repeatReader reads the string repeated n times.
+type repeatReader struct {
+	s   string
+	n   int
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	read := 0
+	for read < len(p) && r.n > 0 {
+		c := copy(p[read:], r.s[r.off:])
+		read += c
+		r.off += c
+		if r.off == len(r.s) {
+			r.off = 0
+			r.n--
+		}
+	}
+	if read == 0 {
+		return 0, io.EOF
+	}
+	return read, nil
+}
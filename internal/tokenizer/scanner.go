@@ -0,0 +1,446 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scanner is a hand-written tokenizer producing the same tokens as the
+// Tokenizer with DefaultRules. It doesn't allocate unless it reports an
+// error.
+type Scanner struct {
+	src       string
+	cursor    int
+	line      int
+	lineStart int
+}
+
+func NewScanner(src string) *Scanner {
+	return &Scanner{
+		src:  src,
+		line: 1,
+	}
+}
+
+// keywords are the identifiers reserved by the language.
+var keywords = map[string]TokenType{
+	"let":      LetKeyword,
+	"def":      DefKeyword,
+	"return":   ReturnKeyword,
+	"if":       IfKeyword,
+	"while":    WhileKeyword,
+	"do":       DoKeyword,
+	"class":    ClassKeyword,
+	"this":     ThisKeyword,
+	"extends":  ExtendsKeyword,
+	"super":    SuperKeyword,
+	"new":      NewKeyword,
+	"for":      ForKeyword,
+	"break":    BreakKeyword,
+	"continue": ContinueKeyword,
+	"try":      TryKeyword,
+	"catch":    CatchKeyword,
+	"finally":  FinallyKeyword,
+	"throw":    ThrowKeyword,
+	"switch":   SwitchKeyword,
+	"case":     CaseKeyword,
+	"default":  DefaultKeyword,
+	"typeof":   TypeofKeyword,
+	"else":     ElseKeyword,
+	"true":     TrueKeyword,
+	"false":    FalseKeyword,
+	"null":     NullKeyword,
+
+	// The keyword operators share the type with the symbol operators of the
+	// same precedence.
+	"instanceof": RelationalOp,
+	"in":         RelationalOp,
+}
+
+// identifierType returns the type of the keyword or Identifier.
+func identifierType(name string) TokenType {
+	if typ, ok := keywords[name]; ok {
+		return typ
+	}
+	return Identifier
+}
+
+func (s *Scanner) NextToken() (Token, error) {
+	s.skipSpace()
+
+	start := s.pos()
+	if s.cursor >= len(s.src) {
+		return Token{Type: EOF, Start: start, End: start}, nil
+	}
+
+	rest := s.src[s.cursor:]
+	typ, n := scanToken(rest)
+	if n == 0 {
+		n, err := scanError(rest, start)
+		s.advance(n)
+		return Token{}, err
+	}
+	s.advance(n)
+
+	return Token{
+		Type:  typ,
+		Value: rest[:n],
+		Start: start,
+		End:   s.pos(),
+	}, nil
+}
+
+// scanToken returns the type and the length of the token at the beginning
+// of the non-empty rest, the length is zero if there is no valid token.
+func scanToken(rest string) (TokenType, int) {
+	switch c := rest[0]; c {
+	case ';':
+		return Semicolon, 1
+	case '{':
+		return OpenCurlyBrace, 1
+	case '}':
+		return CloseCurlyBrace, 1
+	case '(':
+		return OpenParens, 1
+	case ')':
+		return CloseParens, 1
+	case ',':
+		return Comma, 1
+	case ':':
+		return Colon, 1
+	case '?':
+		return Question, 1
+	case '[':
+		return OpenSquare, 1
+	case ']':
+		return CloseSquare, 1
+	case '.':
+		if n := scanNumber(rest); n > 0 {
+			return Number, n
+		}
+		return Dot, 1
+	case '"', '\'':
+		return String, scanString(rest)
+	case '=':
+		switch peek(rest, 1) {
+		case '=':
+			if peek(rest, 2) == '=' {
+				return EqualityOp, 3
+			}
+			return EqualityOp, 2
+		case '>':
+			return Arrow, 2
+		}
+		return SimpleAssign, 1
+	case '!':
+		if peek(rest, 1) == '=' {
+			if peek(rest, 2) == '=' {
+				return EqualityOp, 3
+			}
+			return EqualityOp, 2
+		}
+		return NotLogicalOp, 1
+	case '&', '|':
+		switch peek(rest, 1) {
+		case c:
+			if c == '&' {
+				return AndLogicalOp, 2
+			}
+			return OrLogicalOp, 2
+		case '=':
+			return ComplexAssign, 2
+		}
+		if c == '&' {
+			return BitwiseAndOp, 1
+		}
+		return BitwiseOrOp, 1
+	case '^':
+		if peek(rest, 1) == '=' {
+			return ComplexAssign, 2
+		}
+		return BitwiseXorOp, 1
+	case '~':
+		return BitwiseNotOp, 1
+	case '<', '>':
+		if peek(rest, 1) == c {
+			n := 2
+			if c == '>' && peek(rest, 2) == '>' {
+				n = 3
+			}
+			if peek(rest, n) == '=' {
+				return ComplexAssign, n + 1
+			}
+			return ShiftOp, n
+		}
+		if peek(rest, 1) == '=' {
+			return RelationalOp, 2
+		}
+		return RelationalOp, 1
+	case '*':
+		n := 1
+		if peek(rest, 1) == '*' {
+			n = 2
+		}
+		if peek(rest, n) == '=' {
+			return ComplexAssign, n + 1
+		}
+		if n == 2 {
+			return ExponentOp, 2
+		}
+		return MultiplicativeOp, 1
+	case '+', '-', '%':
+		switch peek(rest, 1) {
+		case '=':
+			return ComplexAssign, 2
+		case c:
+			if c != '%' {
+				return UpdateOp, 2
+			}
+		}
+		if c == '%' {
+			return MultiplicativeOp, 1
+		}
+		return AdditiveOp, 1
+	case '/':
+		switch peek(rest, 1) {
+		case '/':
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				return Comment, end
+			}
+			return Comment, len(rest)
+		case '*':
+			if end := strings.Index(rest[2:], "*/"); end >= 0 {
+				return Comment, end + 4
+			}
+		case '=':
+			return ComplexAssign, 2
+		}
+		return MultiplicativeOp, 1
+	default:
+		if isDigit(c) {
+			return Number, scanNumber(rest)
+		}
+		if n := scanIdentifier(rest); n > 0 {
+			return identifierType(rest[:n]), n
+		}
+	}
+
+	return "", 0
+}
+
+// scanNumber returns the length of the numeric literal at the beginning of
+// s or zero if there is none.
+func scanNumber(s string) int {
+	if len(s) > 2 && s[0] == '0' {
+		var isBaseDigit func(byte) bool
+		switch s[1] {
+		case 'x', 'X':
+			isBaseDigit = isHexDigit
+		case 'o', 'O':
+			isBaseDigit = isOctalDigit
+		case 'b', 'B':
+			isBaseDigit = isBinaryDigit
+		}
+		if isBaseDigit != nil {
+			if n := scanDigits(s[2:], isBaseDigit); n > 0 {
+				return 2 + n
+			}
+		}
+	}
+
+	n := scanDigits(s, isDigit)
+	if peek(s, n) == '.' {
+		if frac := scanDigits(s[n+1:], isDigit); frac > 0 {
+			n += 1 + frac
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+
+	if c := peek(s, n); c == 'e' || c == 'E' {
+		sign := 0
+		if c := peek(s, n+1); c == '+' || c == '-' {
+			sign = 1
+		}
+		if exp := scanDigits(s[n+1+sign:], isDigit); exp > 0 {
+			n += 1 + sign + exp
+		}
+	}
+
+	return n
+}
+
+// scanDigits returns the length of the digits at the beginning of s, the
+// digits may be separated by single underscores.
+func scanDigits(s string, isBaseDigit func(byte) bool) int {
+	if len(s) == 0 || !isBaseDigit(s[0]) {
+		return 0
+	}
+
+	n := 1
+	for n < len(s) {
+		switch {
+		case isBaseDigit(s[n]):
+			n++
+		case s[n] == '_' && n+1 < len(s) && isBaseDigit(s[n+1]):
+			n += 2
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// scanString returns the length of the string literal at the beginning of
+// s or zero if it is not terminated on the same line.
+func scanString(s string) int {
+	quote := s[0]
+	for n := 1; n < len(s); n++ {
+		switch s[n] {
+		case quote:
+			return n + 1
+		case '\n':
+			return 0
+		case '\\':
+			if n+1 < len(s) && s[n+1] != '\n' {
+				_, size := utf8.DecodeRuneInString(s[n+1:])
+				n += size
+			}
+		}
+	}
+	return 0
+}
+
+// skipSpace moves the cursor past the whitespace.
+func (s *Scanner) skipSpace() {
+	for s.cursor < len(s.src) {
+		switch s.src[s.cursor] {
+		case '\n':
+			s.line++
+			s.lineStart = s.cursor + 1
+		case ' ', '\t', '\r', '\f':
+		default:
+			return
+		}
+		s.cursor++
+	}
+}
+
+// scanError returns the error describing the invalid input at the
+// beginning of rest and the length of the input to skip.
+func scanError(rest string, start Position) (int, error) {
+	if rest[0] == '"' || rest[0] == '\'' {
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+
+		return end, &ErrUnterminatedString{
+			Position:   start,
+			CodeString: rest[:end],
+		}
+	}
+
+	_, size := utf8.DecodeRuneInString(rest)
+	return size, &ErrUnexpectedToken{
+		Position:   start,
+		CodeString: rest[:size],
+	}
+}
+
+// advance moves the cursor n bytes forward keeping track of line breaks.
+func (s *Scanner) advance(n int) {
+	for i := s.cursor; i < s.cursor+n; i++ {
+		if s.src[i] == '\n' {
+			s.line++
+			s.lineStart = i + 1
+		}
+	}
+	s.cursor += n
+}
+
+func (s *Scanner) pos() Position {
+	return Position{
+		Offset: s.cursor,
+		Line:   s.line,
+		Column: s.cursor - s.lineStart + 1,
+	}
+}
+
+// peek returns the byte at the index of s or zero if it is out of range.
+func peek(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isOctalDigit(c byte) bool {
+	return '0' <= c && c <= '7'
+}
+
+func isBinaryDigit(c byte) bool {
+	return c == '0' || c == '1'
+}
+
+// scanIdentifier returns the length of the identifier at the beginning of
+// s or zero if there is none.
+func scanIdentifier(s string) int {
+	r, n := decodeRune(s)
+	if !isIdentifierStart(r) {
+		return 0
+	}
+
+	for n < len(s) {
+		r, size := decodeRune(s[n:])
+		if !isIdentifierPart(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func decodeRune(s string) (rune, int) {
+	if s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
+
+// isIdentifierStart reports whether the rune may start an identifier, it is
+// either $, _ or has the Unicode ID_Start property.
+func isIdentifierStart(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_', r == '$':
+		return true
+	case r < utf8.RuneSelf:
+		return false
+	}
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// isIdentifierPart reports whether the rune may continue an identifier, it
+// is either $, _, the zero width (non-)joiner or has the Unicode
+// ID_Continue property.
+func isIdentifierPart(r rune) bool {
+	switch {
+	case isIdentifierStart(r), '0' <= r && r <= '9', r == '\u200C', r == '\u200D':
+		return true
+	case r < utf8.RuneSelf:
+		return false
+	}
+	return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
@@ -0,0 +1,201 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nextTokener interface {
+	NextToken() (Token, error)
+}
+
+type result struct {
+	tok Token
+	err string
+}
+
+func tokenize(t nextTokener) []result {
+	var results []result
+	for {
+		tok, err := t.NextToken()
+		r := result{tok: tok}
+		if err != nil {
+			r.err = err.Error()
+		}
+		results = append(results, r)
+		if err == nil && tok.Type == EOF {
+			return results
+		}
+	}
+}
+
+func TestScanner_NextToken(t *testing.T) {
+	tests := []string{
+		``,
+		"  \t\r\n\f ",
+		`let x = 42; def f(a, b) { return a.b[c]; }`,
+		`let o = {a: 1, "b": [2], [c]: d, e, f() {}};`,
+		`f(x => x, (a, b) => { return a; }, def (c) {}); a ==> b = > c =>`,
+		`a ? b : c ? d : e; ?? ?: x?y:z`,
+		`class A extends B { def constructor() { super(); this.x = new C(); } }`,
+		`if (a >= b && c <= d || !e) {} else while (x != y) do {} while (x == y); for (;;) {}`,
+		`a += 1; a -= 1; a *= 1; a /= 1; a + b - c * d / e < f > g`,
+		`letter define iffy classy new2 _x x_1 true false null truest`,
+		`outer: while (x) { break; continue outer; } breaks continued`,
+		`try { throw e; } catch (e) {} finally {} trying thrown`,
+		`switch (x) { case 1: break; default: } switches cases defaults`,
+		`a % b ** c << d >> e >>> f & g | h ^ ~i && j || k`,
+		`a %= 1; a **= 2; a <<= 3; a >>= 4; a >>>= 5; a &= 6; a |= 7; a ^= 8; a>>>=b`,
+		`++i; i++; --i; i--; a+++b a---b a+-+b a-- -b ++=`,
+		`a === b !== c ==== d typeof x instanceof C "k" in o typeofx inside instanceOf`,
+		`délai $el _$ a$b Ωmega x٣ 名前 let٣ letü ü a‍b 1abc`,
+		`0xFF 0X1_f 0o17 0b101 0x 0b2 1_000 1__0 1_ 3.14 .5 1. 1.e5 1e9 2.5E-3 1e+ 1e 0.0_1 a.5 1abc`,
+		`"hello" 'it\'s' "a\"b" "\\" "\u{1F600}" "ü" 'x"y' "a\q"`,
+		"\"unterminated\nlet x;\n'also",
+		"\"\\\nx\"",
+		`// line comment
+/* block
+comment */ x /**/ y /*/ z */ / * /= //`,
+		`/* unterminated`,
+		"a @ b # ü & | c",
+		"x\n\n  y\n\tz",
+	}
+
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			want := tokenize(NewTokenizer(DefaultRules, in))
+			got := tokenize(NewScanner(in))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestScanner_NextToken_Identifier(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantType TokenType
+		wantLen  int
+	}{
+		{"délai", Identifier, 6},
+		{"$el", Identifier, 3},
+		{"℘x", Identifier, 4},  // Other_ID_Start
+		{"a·b", Identifier, 4}, // Other_ID_Continue
+		{"a\u200Db", Identifier, 5},
+		{"let", LetKeyword, 3},
+		{"letü", Identifier, 5},
+		{"instanceof", RelationalOp, 10},
+		{"in", RelationalOp, 2},
+		{"let·", Identifier, 5},
+		{"·", "", 0},
+		{"1a", Number, 1},
+		{"a⁄b", Identifier, 1}, // Pattern_Syntax
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			tok, err := NewScanner(tc.in).NextToken()
+			if tc.wantLen == 0 {
+				assert.EqualError(t, err, fmt.Sprintf("unexpected character %q", tc.in))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantType, tok.Type)
+			assert.Equal(t, tc.in[:tc.wantLen], tok.Value)
+		})
+	}
+}
+
+func TestScanner_NextToken_LongTrivia(t *testing.T) {
+	in := strings.Repeat("// comment\n/* block */   \n", 100000) + "x"
+
+	s := NewScanner(in)
+	n := 0
+	for {
+		tok, err := s.NextToken()
+		require.NoError(t, err)
+		if tok.Type != Comment {
+			assert.Equal(t, Token{Type: Identifier, Value: "x", Start: Position{len(in) - 1, 200001, 1}, End: Position{len(in), 200001, 2}}, tok)
+			break
+		}
+		n++
+	}
+	assert.Equal(t, 200000, n)
+}
+
+func TestScanner_NextToken_Allocs(t *testing.T) {
+	in := benchmarkSource(10)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		s := NewScanner(in)
+		for {
+			tok, err := s.NextToken()
+			require.NoError(t, err)
+			if tok.Type == EOF {
+				break
+			}
+		}
+	})
+	// At most the Scanner itself
+	assert.LessOrEqual(t, allocs, 1.0)
+}
+
+func BenchmarkTokenizer(b *testing.B) {
+	benchmarkNextToken(b, func(in string) nextTokener {
+		return NewTokenizer(DefaultRules, in)
+	})
+}
+
+func BenchmarkScanner(b *testing.B) {
+	benchmarkNextToken(b, func(in string) nextTokener {
+		return NewScanner(in)
+	})
+}
+
+func benchmarkNextToken(b *testing.B, newTokenizer func(string) nextTokener) {
+	in := benchmarkSource(100)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		t := newTokenizer(in)
+		for {
+			tok, err := t.NextToken()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if tok.Type == EOF {
+				break
+			}
+		}
+	}
+}
+
+func benchmarkSource(n int) string {
+	const chunk = `// Point is a point on the plane.
+class Point extends Base {
+	def constructor(x, y) {
+		super();
+		this.x = x;
+		this.y = y;
+	}
+
+	/* distance returns the squared distance */
+	def distance(other) {
+		let dx = this.x - other.x, dy = this.y - other.y;
+		return dx * dx + dy * dy;
+	}
+}
+
+for (let i = 0; i < 1_000; i += 1) {
+	if (i >= 0x10 && i != 3.5e2 || !false) {
+		print("point", new Point(i, 'y').distance(null));
+	}
+}
+`
+	return strings.Repeat(chunk, n)
+}
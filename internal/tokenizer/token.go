@@ -3,6 +3,16 @@ package tokenizer
 type Token struct {
 	Type  TokenType
 	Value string
+	Start Position
+	End   Position
+}
+
+// Position is a location in the source code. Offset is a 0-based byte offset,
+// Line and Column are 1-based, Column is counted in bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
 }
 
 type TokenType string
@@ -10,14 +20,19 @@ type TokenType string
 const (
 	// EOF is a special type of token that indicates the end of the file
 	EOF TokenType = "EOF"
-	// Skip are tokens such as whitespace or comments
-	Skip             TokenType = "Skip"
+	// Skip are tokens such as whitespace
+	Skip TokenType = "Skip"
+	// Comment is either a // line comment or a /* block comment */
+	Comment          TokenType = "Comment"
 	Semicolon        TokenType = ";"
 	OpenCurlyBrace   TokenType = "{"
 	CloseCurlyBrace  TokenType = "}"
 	OpenParens       TokenType = "("
 	CloseParens      TokenType = ")"
 	Comma            TokenType = ","
+	Colon            TokenType = ":"
+	Question         TokenType = "?"
+	Arrow            TokenType = "=>"
 	Dot              TokenType = "."
 	OpenSquare       TokenType = "["
 	CloseSquare      TokenType = "]"
@@ -33,6 +48,16 @@ const (
 	SuperKeyword     TokenType = "super"
 	NewKeyword       TokenType = "new"
 	ForKeyword       TokenType = "for"
+	BreakKeyword     TokenType = "break"
+	ContinueKeyword  TokenType = "continue"
+	TryKeyword       TokenType = "try"
+	CatchKeyword     TokenType = "catch"
+	FinallyKeyword   TokenType = "finally"
+	ThrowKeyword     TokenType = "throw"
+	SwitchKeyword    TokenType = "switch"
+	CaseKeyword      TokenType = "case"
+	DefaultKeyword   TokenType = "default"
+	TypeofKeyword    TokenType = "typeof"
 	ElseKeyword      TokenType = "else"
 	TrueKeyword      TokenType = "true"
 	FalseKeyword     TokenType = "false"
@@ -40,15 +65,22 @@ const (
 	Number           TokenType = "Number"           // 10
 	String           TokenType = "String"           // "hello"
 	Identifier       TokenType = "Identifier"       // name of variable
-	EqualityOp       TokenType = "EqualityOp"       // == !=
+	EqualityOp       TokenType = "EqualityOp"       // == != === !==
 	SimpleAssign     TokenType = "="                // =
-	ComplexAssign    TokenType = "ComplexAssign"    // *= /= += -=
-	RelationalOp     TokenType = "RelationalOp"     // > < >= <=
+	ComplexAssign    TokenType = "ComplexAssign"    // *= /= %= += -= **= <<= >>= >>>= &= |= ^=
+	RelationalOp     TokenType = "RelationalOp"     // > < >= <= instanceof in
 	AndLogicalOp     TokenType = "AndLogicalOp"     // &&
 	OrLogicalOp      TokenType = "OrLogicalOp"      // ||
 	NotLogicalOp     TokenType = "NotLogicalOp"     // !
 	AdditiveOp       TokenType = "AdditiveOp"       // + or -
-	MultiplicativeOp TokenType = "MultiplicativeOp" // * or /
+	UpdateOp         TokenType = "UpdateOp"         // ++ or --
+	MultiplicativeOp TokenType = "MultiplicativeOp" // * / %
+	ExponentOp       TokenType = "ExponentOp"       // **
+	ShiftOp          TokenType = "ShiftOp"          // << >> >>>
+	BitwiseAndOp     TokenType = "BitwiseAndOp"     // &
+	BitwiseOrOp      TokenType = "BitwiseOrOp"      // |
+	BitwiseXorOp     TokenType = "BitwiseXorOp"     // ^
+	BitwiseNotOp     TokenType = "BitwiseNotOp"     // ~
 )
 
 func (t TokenType) MarshalJSON() ([]byte, error) {
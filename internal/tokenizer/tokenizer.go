@@ -1,6 +1,10 @@
 package tokenizer
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
 
 type Rule struct {
 	Type   TokenType
@@ -9,95 +13,166 @@ type Rule struct {
 
 var DefaultRules = []Rule{
 	{Type: Skip, Regexp: regexp.MustCompile(`^\s+`)},
-	{Type: Skip, Regexp: regexp.MustCompile(`^//.*`)},
-	{Type: Skip, Regexp: regexp.MustCompile(`^/\*[\s\S]*?\*/`)},
+	{Type: Comment, Regexp: regexp.MustCompile(`^//.*`)},
+	{Type: Comment, Regexp: regexp.MustCompile(`^/\*[\s\S]*?\*/`)},
 	{Type: Semicolon, Regexp: regexp.MustCompile(`^;`)},
 	{Type: OpenCurlyBrace, Regexp: regexp.MustCompile(`^{`)},
 	{Type: CloseCurlyBrace, Regexp: regexp.MustCompile(`^}`)},
 	{Type: OpenParens, Regexp: regexp.MustCompile(`^\(`)},
 	{Type: CloseParens, Regexp: regexp.MustCompile(`^\)`)},
 	{Type: Comma, Regexp: regexp.MustCompile(`^,`)},
+	{Type: Colon, Regexp: regexp.MustCompile(`^:`)},
+	{Type: Question, Regexp: regexp.MustCompile(`^\?`)},
+	{Type: Number, Regexp: regexp.MustCompile(`^0[xX][\da-fA-F](_?[\da-fA-F])*`)},
+	{Type: Number, Regexp: regexp.MustCompile(`^0[oO][0-7](_?[0-7])*`)},
+	{Type: Number, Regexp: regexp.MustCompile(`^0[bB][01](_?[01])*`)},
+	{Type: Number, Regexp: regexp.MustCompile(`^(\d(_?\d)*(\.\d(_?\d)*)?|\.\d(_?\d)*)([eE][+\-]?\d(_?\d)*)?`)},
 	{Type: Dot, Regexp: regexp.MustCompile(`^\.`)},
 	{Type: OpenSquare, Regexp: regexp.MustCompile(`^\[`)},
 	{Type: CloseSquare, Regexp: regexp.MustCompile(`^]`)},
-	{Type: LetKeyword, Regexp: regexp.MustCompile(`^\blet\b`)},
-	{Type: DefKeyword, Regexp: regexp.MustCompile(`^\bdef\b`)},
-	{Type: ReturnKeyword, Regexp: regexp.MustCompile(`^\breturn\b`)},
-	{Type: IfKeyword, Regexp: regexp.MustCompile(`^\bif\b`)},
-	{Type: WhileKeyword, Regexp: regexp.MustCompile(`^\bwhile\b`)},
-	{Type: DoKeyword, Regexp: regexp.MustCompile(`^\bdo\b`)},
-	{Type: ClassKeyword, Regexp: regexp.MustCompile(`^\bclass\b`)},
-	{Type: ThisKeyword, Regexp: regexp.MustCompile(`^\bthis\b`)},
-	{Type: ExtendsKeyword, Regexp: regexp.MustCompile(`^\bextends\b`)},
-	{Type: SuperKeyword, Regexp: regexp.MustCompile(`^\bsuper\b`)},
-	{Type: NewKeyword, Regexp: regexp.MustCompile(`^\bnew\b`)},
-	{Type: ForKeyword, Regexp: regexp.MustCompile(`^\bfor\b`)},
-	{Type: ElseKeyword, Regexp: regexp.MustCompile(`^\belse\b`)},
-	{Type: TrueKeyword, Regexp: regexp.MustCompile(`^\btrue\b`)},
-	{Type: FalseKeyword, Regexp: regexp.MustCompile(`^\bfalse\b`)},
-	{Type: NullKeyword, Regexp: regexp.MustCompile(`^\bnull\b`)},
-	{Type: Number, Regexp: regexp.MustCompile(`^\d+`)},
-	{Type: String, Regexp: regexp.MustCompile(`^"[^"]*"`)},
-	{Type: String, Regexp: regexp.MustCompile(`^'[^"]*'`)},
-	{Type: Identifier, Regexp: regexp.MustCompile(`^\w+`)},
-	{Type: EqualityOp, Regexp: regexp.MustCompile(`^[=!]=`)},
+	{Type: String, Regexp: regexp.MustCompile(`^"([^"\\\n]|\\.)*"`)},
+	{Type: String, Regexp: regexp.MustCompile(`^'([^'\\\n]|\\.)*'`)},
+	// Go regexps lack the Other_ID_Start and Other_ID_Continue properties,
+	// the few characters having them are not identifiers here
+	{Type: Identifier, Regexp: regexp.MustCompile(`^[\pL\p{Nl}$_][\pL\p{Nl}\p{Mn}\p{Mc}\p{Nd}\p{Pc}$_\x{200C}\x{200D}]*`)},
+	{Type: EqualityOp, Regexp: regexp.MustCompile(`^[=!]==?`)},
+	{Type: Arrow, Regexp: regexp.MustCompile(`^=>`)},
 	{Type: SimpleAssign, Regexp: regexp.MustCompile(`^=`)},
-	{Type: ComplexAssign, Regexp: regexp.MustCompile(`^[+\-*/]=`)},
+	{Type: ComplexAssign, Regexp: regexp.MustCompile(`^(\*\*|<<|>>>?|[+\-*/%&|^])=`)},
 	{Type: NotLogicalOp, Regexp: regexp.MustCompile(`^!`)},
 	{Type: AndLogicalOp, Regexp: regexp.MustCompile(`^&&`)},
 	{Type: OrLogicalOp, Regexp: regexp.MustCompile(`^\|\|`)},
+	{Type: BitwiseAndOp, Regexp: regexp.MustCompile(`^&`)},
+	{Type: BitwiseOrOp, Regexp: regexp.MustCompile(`^\|`)},
+	{Type: BitwiseXorOp, Regexp: regexp.MustCompile(`^\^`)},
+	{Type: BitwiseNotOp, Regexp: regexp.MustCompile(`^~`)},
+	{Type: ShiftOp, Regexp: regexp.MustCompile(`^(<<|>>>?)`)},
 	{Type: RelationalOp, Regexp: regexp.MustCompile(`^[<>]=?`)},
+	{Type: UpdateOp, Regexp: regexp.MustCompile(`^(\+\+|--)`)},
 	{Type: AdditiveOp, Regexp: regexp.MustCompile(`^[+\-]`)},
-	{Type: MultiplicativeOp, Regexp: regexp.MustCompile(`^[*/]`)},
+	{Type: ExponentOp, Regexp: regexp.MustCompile(`^\*\*`)},
+	{Type: MultiplicativeOp, Regexp: regexp.MustCompile(`^[*/%]`)},
 }
 
+// Tokenizer matches the rules in order at the cursor, the first match is
+// the token. It is the reference implementation of the language the Scanner
+// implements and allows to experiment with the rules.
 type Tokenizer struct {
-	expr   string
-	cursor int
-	rules  []Rule
+	expr      string
+	cursor    int
+	line      int
+	lineStart int
+	rules     []Rule
 }
 
 func NewTokenizer(rules []Rule, expr string) *Tokenizer {
 	return &Tokenizer{
 		expr:   expr,
 		cursor: 0,
+		line:   1,
 		rules:  rules,
 	}
 }
 
-func (t *Tokenizer) NextToken() (*Token, error) {
-	if t.cursor >= len(t.expr) {
-		return &Token{
-			Type: EOF,
-		}, nil
+// NextToken returns the next token, the Skip ones are left out.
+func (t *Tokenizer) NextToken() (Token, error) {
+	for t.cursor < len(t.expr) {
+		tok, ok := t.matchRules()
+		if !ok {
+			return Token{}, t.error()
+		}
+		if tok.Type != Skip {
+			return tok, nil
+		}
 	}
 
+	return Token{
+		Type:  EOF,
+		Start: t.pos(),
+		End:   t.pos(),
+	}, nil
+}
+
+// matchRules returns the token matching the first of the rules, the
+// identifiers reserved as keywords get the type of the keyword.
+func (t *Tokenizer) matchRules() (Token, bool) {
 	for _, spec := range t.rules {
 		rest := t.expr[t.cursor:]
+		start := t.pos()
 
 		if matched, ok := t.match(spec.Regexp, rest); ok {
-			if spec.Type == Skip {
-				return t.NextToken()
+			typ := spec.Type
+			if typ == Identifier {
+				typ = identifierType(matched)
 			}
 
-			return &Token{
-				Type:  spec.Type,
+			return Token{
+				Type:  typ,
 				Value: matched,
-			}, nil
+				Start: start,
+				End:   t.pos(),
+			}, true
+		}
+	}
+
+	return Token{}, false
+}
+
+// error skips the offending input and returns the error describing it.
+func (t *Tokenizer) error() error {
+	start := t.pos()
+
+	// None of the string rules match an opening quote only if the literal
+	// lacks the closing one on the same line, the rest of the line is
+	// skipped.
+	if rest := t.expr[t.cursor:]; rest[0] == '"' || rest[0] == '\'' {
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		t.advance(end)
+
+		return &ErrUnterminatedString{
+			Position:   start,
+			CodeString: rest[:end],
 		}
 	}
 
-	return nil, &ErrUnexpectedToken{
-		Position:   t.cursor,
-		CodeString: t.expr[t.cursor:],
+	// Skip the offending character so that the caller may carry on
+	// tokenizing the rest of the input after reporting the error.
+	_, size := utf8.DecodeRuneInString(t.expr[t.cursor:])
+	t.advance(size)
+
+	return &ErrUnexpectedToken{
+		Position:   start,
+		CodeString: t.expr[start.Offset:t.cursor],
 	}
 }
 
 func (t *Tokenizer) match(re *regexp.Regexp, s string) (string, bool) {
 	if m := re.FindStringIndex(s); m != nil {
-		t.cursor += m[1] - m[0]
+		t.advance(m[1] - m[0])
 		return s[m[0]:m[1]], true
 	}
 
 	return "", false
 }
+
+// advance moves the cursor n bytes forward keeping track of line breaks.
+func (t *Tokenizer) advance(n int) {
+	for i := t.cursor; i < t.cursor+n; i++ {
+		if t.expr[i] == '\n' {
+			t.line++
+			t.lineStart = i + 1
+		}
+	}
+	t.cursor += n
+}
+
+func (t *Tokenizer) pos() Position {
+	return Position{
+		Offset: t.cursor,
+		Line:   t.line,
+		Column: t.cursor - t.lineStart + 1,
+	}
+}
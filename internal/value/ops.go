@@ -0,0 +1,192 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+// BinaryOp applies the binary operator to the operands.
+func BinaryOp(op ast.BinaryOp, left, right Value) (Value, error) {
+	// The values are never converted to be compared, so the strict equality
+	// operators agree with the plain ones.
+	switch op {
+	case ast.EqBinaryOp, ast.StrictEqBinaryOp:
+		return IsEqual(left, right), nil
+	case ast.NeqBinaryOp, ast.StrictNeqBinaryOp:
+		return !IsEqual(left, right), nil
+	case ast.InstanceofBinaryOp:
+		class, ok := right.(*Class)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a class", TypeName(right))
+		}
+		inst, ok := left.(*Instance)
+		return ok && inst.Class.isSubclassOf(class), nil
+	case ast.InBinaryOp:
+		return HasProp(right, ToString(left))
+	}
+
+	if op == ast.AddBinaryOp {
+		_, lstr := left.(string)
+		_, rstr := right.(string)
+		if lstr || rstr {
+			return ToString(left) + ToString(right), nil
+		}
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case ast.GtBinaryOp:
+				return l > r, nil
+			case ast.LtBinaryOp:
+				return l < r, nil
+			case ast.GteBinaryOp:
+				return l >= r, nil
+			case ast.LteBinaryOp:
+				return l <= r, nil
+			}
+		}
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("bad operand types for %s: %s and %s", op, TypeName(left), TypeName(right))
+	}
+
+	switch op {
+	case ast.AddBinaryOp:
+		return l + r, nil
+	case ast.SubBinaryOp:
+		return l - r, nil
+	case ast.MulBinaryOp:
+		return l * r, nil
+	case ast.DivBinaryOp:
+		return l / r, nil
+	case ast.ModBinaryOp:
+		return math.Mod(l, r), nil
+	case ast.ExpBinaryOp:
+		return math.Pow(l, r), nil
+	case ast.ShlBinaryOp:
+		return float64(ToInt32(l) << (uint32(ToInt32(r)) & 31)), nil
+	case ast.ShrBinaryOp:
+		return float64(ToInt32(l) >> (uint32(ToInt32(r)) & 31)), nil
+	case ast.UShrBinaryOp:
+		return float64(uint32(ToInt32(l)) >> (uint32(ToInt32(r)) & 31)), nil
+	case ast.BitAndBinaryOp:
+		return float64(ToInt32(l) & ToInt32(r)), nil
+	case ast.BitOrBinaryOp:
+		return float64(ToInt32(l) | ToInt32(r)), nil
+	case ast.BitXorBinaryOp:
+		return float64(ToInt32(l) ^ ToInt32(r)), nil
+	case ast.GtBinaryOp:
+		return l > r, nil
+	case ast.LtBinaryOp:
+		return l < r, nil
+	case ast.GteBinaryOp:
+		return l >= r, nil
+	case ast.LteBinaryOp:
+		return l <= r, nil
+	default:
+		return nil, fmt.Errorf("unknown binary operator %s", op)
+	}
+}
+
+// HasProp reports whether the object, the instance or the array has the
+// property, the methods of the instance's class count as its properties.
+func HasProp(obj Value, key string) (bool, error) {
+	switch o := obj.(type) {
+	case *Instance:
+		_, ok := o.fields[key]
+		return ok || o.Class.FindMethod(key) != nil, nil
+	case *Object:
+		_, ok := o.fields[key]
+		return ok, nil
+	case *Array:
+		idx, ok := arrayIndex(key)
+		return key == "length" || ok && idx < len(o.Elements), nil
+	}
+
+	return false, fmt.Errorf("can't search for property %q in %s", key, TypeName(obj))
+}
+
+// GetProp reads the property of the value, the methods of the instances and
+// the objects are bound to them. The missing properties are null.
+func GetProp(obj Value, key string) (Value, error) {
+	switch o := obj.(type) {
+	case *Instance:
+		if v, ok := o.fields[key]; ok {
+			return v, nil
+		}
+		if m := o.Class.FindMethod(key); m != nil {
+			return m.Bind(o), nil
+		}
+		return nil, nil
+	case *Object:
+		return o.Get(key), nil
+	case *Array:
+		if key == "length" {
+			return float64(len(o.Elements)), nil
+		}
+		if idx, ok := arrayIndex(key); ok && idx < len(o.Elements) {
+			return o.Elements[idx], nil
+		}
+		return nil, nil
+	case string:
+		if key == "length" {
+			return float64(utf8.RuneCountInString(o)), nil
+		}
+		if idx, err := strconv.Atoi(key); err == nil {
+			runes := []rune(o)
+			if idx >= 0 && idx < len(runes) {
+				return string(runes[idx]), nil
+			}
+			return nil, nil
+		}
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("can't read property %q of %s", key, TypeName(obj))
+}
+
+// SetProp sets the property of the instance, the object or the array, the
+// arrays grow to the assigned index.
+func SetProp(obj Value, key string, v Value) error {
+	switch o := obj.(type) {
+	case *Instance:
+		o.fields[key] = v
+		return nil
+	case *Object:
+		o.Set(key, v)
+		return nil
+	case *Array:
+		idx, ok := arrayIndex(key)
+		if !ok {
+			break
+		}
+		if idx >= maxArrayLength {
+			return fmt.Errorf("array index %d out of range", idx)
+		}
+		for len(o.Elements) <= idx {
+			o.Elements = append(o.Elements, nil)
+		}
+		o.Elements[idx] = v
+		return nil
+	}
+
+	return fmt.Errorf("can't set property %q of %s", key, TypeName(obj))
+}
+
+// arrayIndex returns the index the key stands for, the key must be the
+// canonical form of a non-negative integer.
+func arrayIndex(key string) (int, bool) {
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 || strconv.Itoa(idx) != key {
+		return 0, false
+	}
+	return idx, true
+}
@@ -0,0 +1,254 @@
+package value
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is a runtime value of the program, the interpreter and the VM share
+// the values and their semantics. It is one of:
+//
+//	nil        - null
+//	bool       - boolean
+//	float64    - number
+//	string     - string
+//	Function   - user defined function or method of the engine
+//	*Builtin   - function implemented by the engine
+//	*Class     - class
+//	*Instance  - object created by 'new'
+//	*Array     - array
+//	*Object    - object made by the object literal
+type Value interface{}
+
+// Function is a user defined function, each engine has its own
+// representation of the functions and of the bound methods.
+type Function interface {
+	// String returns the function as it is printed.
+	String() string
+	// Bind returns the method bound to the receiver it is read from, the
+	// functions which aren't methods or are bound already are returned as
+	// they are.
+	Bind(receiver Value) Function
+	// Equal reports whether the functions are the same, the methods bound
+	// to the same receiver more than once are equal.
+	Equal(other Function) bool
+}
+
+type Builtin struct {
+	Name string
+	Fn   func(args []Value) (Value, error)
+}
+
+type Class struct {
+	Name    string
+	Super   *Class
+	Methods map[string]Function
+}
+
+func NewClass(name string) *Class {
+	return &Class{
+		Name:    name,
+		Methods: map[string]Function{},
+	}
+}
+
+// FindMethod looks the method up in the class and its ancestors.
+func (c *Class) FindMethod(name string) Function {
+	for cls := c; cls != nil; cls = cls.Super {
+		if m, ok := cls.Methods[name]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// isSubclassOf reports whether the class is the other one or inherits from
+// it.
+func (c *Class) isSubclassOf(other *Class) bool {
+	for cls := c; cls != nil; cls = cls.Super {
+		if cls == other {
+			return true
+		}
+	}
+	return false
+}
+
+type Instance struct {
+	Class  *Class
+	fields map[string]Value
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{
+		Class:  class,
+		fields: map[string]Value{},
+	}
+}
+
+// maxArrayLength limits the length an array may grow to by assigning past
+// its end.
+const maxArrayLength = 1 << 24
+
+// Array is the array made by the array literal, the holes are null.
+type Array struct {
+	Elements []Value
+}
+
+// Object is the object made by the object literal, it keeps the order the
+// properties are added in.
+type Object struct {
+	keys   []string
+	fields map[string]Value
+}
+
+func NewObject() *Object {
+	return &Object{fields: map[string]Value{}}
+}
+
+// Get returns the property of the object, the methods are bound to it.
+func (o *Object) Get(key string) Value {
+	v := o.fields[key]
+	if fn, ok := v.(Function); ok {
+		return fn.Bind(o)
+	}
+	return v
+}
+
+func (o *Object) Set(key string, v Value) {
+	if _, ok := o.fields[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.fields[key] = v
+}
+
+// format formats the arrays and the objects with their elements, the ones
+// containing themselves are printed as [...] and {...} where they repeat.
+func format(v Value, seen map[Value]bool) string {
+	switch v := v.(type) {
+	case *Array:
+		if seen[v] {
+			return "[...]"
+		}
+		seen[v] = true
+		defer delete(seen, v)
+
+		strs := make([]string, len(v.Elements))
+		for i, el := range v.Elements {
+			strs[i] = format(el, seen)
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	case *Object:
+		if seen[v] {
+			return "{...}"
+		}
+		seen[v] = true
+		defer delete(seen, v)
+
+		strs := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			strs[i] = key + ": " + format(v.fields[key], seen)
+		}
+		return "{" + strings.Join(strs, ", ") + "}"
+	default:
+		return ToString(v)
+	}
+}
+
+func IsTruthy(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+func IsEqual(a, b Value) bool {
+	switch a := a.(type) {
+	case Function:
+		if b, ok := b.(Function); ok {
+			return a.Equal(b)
+		}
+		return false
+	default:
+		return a == b
+	}
+}
+
+func TypeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case Function, *Builtin:
+		return "function"
+	case *Class:
+		return "class"
+	case *Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func ToString(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return FormatNumber(v)
+	case string:
+		return v
+	case Function:
+		return v.String()
+	case *Builtin:
+		return "<builtin " + v.Name + ">"
+	case *Class:
+		return "<class " + v.Name + ">"
+	case *Instance:
+		return "<" + v.Class.Name + " instance>"
+	case *Array, *Object:
+		return format(v, map[Value]bool{})
+	default:
+		return "<unknown>"
+	}
+}
+
+func FormatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == math.Trunc(f) && math.Abs(f) < 1e21:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// ToInt32 converts the number to the 32-bit integer the bitwise operators
+// work on, the out of range values wrap around and NaN and the infinities
+// become zero.
+func ToInt32(f float64) int32 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return int32(uint32(int64(math.Mod(math.Trunc(f), 1<<32))))
+}
@@ -0,0 +1,47 @@
+package value
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{-1.5, "-1.5"},
+		{1e20, "100000000000000000000"},
+		{1e21, "1e+21"},
+		{9223372036854775807, "9223372036854776000"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "Infinity"},
+		{math.Inf(-1), "-Infinity"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, FormatNumber(tc.in))
+	}
+}
+
+func TestToInt32(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want int32
+	}{
+		{1.9, 1},
+		{-1.9, -1},
+		{1 << 31, math.MinInt32},
+		{1<<32 + 5, 5},
+		{-(1<<32 + 5), -5},
+		{math.NaN(), 0},
+		{math.Inf(1), 0},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, ToInt32(tc.in), "%v", tc.in)
+	}
+}
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+)
+
+// RuntimeError is an error raised while executing the program, it points to
+// the source position of the failed instruction.
+type RuntimeError struct {
+	Pos ast.Position
+	Msg string
+}
+
+func (e *RuntimeError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/bytecode"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/value"
+)
+
+// Value is a runtime value of the program, see value.Value. The user
+// defined functions are *Closure and the methods bound to the instances
+// and the objects are *BoundMethod.
+type Value = value.Value
+
+type Closure struct {
+	Fn       *bytecode.Function
+	upvalues []*upvalue
+	// class is the class the method is defined in, nil for functions
+	class *value.Class
+	// method is set for the methods of the classes and the object literals,
+	// they are bound to the instance or the object they are read from
+	method bool
+}
+
+func (c *Closure) String() string {
+	return c.Fn.String()
+}
+
+func (c *Closure) Bind(receiver Value) value.Function {
+	if !c.method {
+		return c
+	}
+	return &BoundMethod{Receiver: receiver, Method: c}
+}
+
+func (c *Closure) Equal(other value.Function) bool {
+	return other == c
+}
+
+// upvalue is a variable captured by a closure. While the variable is still
+// on the stack the upvalue refers to its slot, once the variable goes out
+// of scope the value is moved into the upvalue.
+type upvalue struct {
+	slot   int
+	closed Value
+	open   bool
+	next   *upvalue
+}
+
+// BoundMethod is the method of a class or an object literal bound to the
+// instance or the object.
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+func (m *BoundMethod) String() string {
+	return m.Method.String()
+}
+
+func (m *BoundMethod) Bind(Value) value.Function {
+	return m
+}
+
+func (m *BoundMethod) Equal(other value.Function) bool {
+	b, ok := other.(*BoundMethod)
+	return ok && *m == *b
+}
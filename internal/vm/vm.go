@@ -0,0 +1,534 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/bytecode"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/value"
+)
+
+// maxFrames limits the recursion of the executed program.
+const maxFrames = 10000
+
+type frame struct {
+	closure *Closure
+	ip      int
+	// base is the stack index of the first slot of the frame
+	base int
+	// construct is set for constructors called by 'new', the frame returns
+	// the instance instead of the returned value
+	construct bool
+}
+
+// handler is the code the thrown values are passed to, it is installed by
+// the try statement.
+type handler struct {
+	// frame is the index of the frame the handler belongs to
+	frame int
+	ip    int
+	// stack is the size of the stack to restore before the handler runs
+	stack int
+	// finally is set for the handlers running the finalizer, they get the
+	// thrown value wrapped to throw it further from where it was thrown
+	finally bool
+}
+
+// thrown is the value thrown along with the position of the throw.
+type thrown struct {
+	value Value
+	pos   ast.Position
+}
+
+// VM executes the functions compiled by bytecode.Compile.
+type VM struct {
+	out      io.Writer
+	stack    []Value
+	frames   []frame
+	handlers []handler
+	globals  map[string]Value
+	// openUpvalues is the list of upvalues still pointing to the stack
+	// sorted by slot in descending order
+	openUpvalues *upvalue
+}
+
+func NewVM(out io.Writer) *VM {
+	vm := &VM{
+		out:     out,
+		globals: map[string]Value{},
+	}
+	vm.globals["print"] = &value.Builtin{Name: "print", Fn: vm.print}
+
+	return vm
+}
+
+// Run executes the compiled program. Globals defined by the program are kept
+// between the runs.
+func (vm *VM) Run(fn *bytecode.Function) error {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+
+	script := &Closure{Fn: fn}
+	vm.push(script)
+	vm.frames = append(vm.frames, frame{closure: script})
+
+	return vm.run()
+}
+
+func (vm *VM) print(args []Value) (Value, error) {
+	strs := make([]string, len(args))
+	for n, arg := range args {
+		strs[n] = value.ToString(arg)
+	}
+	_, err := fmt.Fprintln(vm.out, strings.Join(strs, " "))
+	return nil, err
+}
+
+func (vm *VM) run() error {
+	f := &vm.frames[len(vm.frames)-1]
+	chunk := &f.closure.Fn.Chunk
+
+	readByte := func() int {
+		b := chunk.Code[f.ip]
+		f.ip++
+		return int(b)
+	}
+	readUint16 := func() int {
+		v := chunk.ReadUint16(f.ip)
+		f.ip += 2
+		return v
+	}
+
+	for {
+		start := f.ip
+
+		switch op := bytecode.Op(readByte()); op {
+		case bytecode.OpConstant:
+			vm.push(chunk.Constants[readUint16()])
+		case bytecode.OpNull:
+			vm.push(nil)
+		case bytecode.OpTrue:
+			vm.push(true)
+		case bytecode.OpFalse:
+			vm.push(false)
+		case bytecode.OpArray:
+			n := readUint16()
+			elements := make([]Value, n)
+			copy(elements, vm.stack[len(vm.stack)-n:])
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			vm.push(&value.Array{Elements: elements})
+		case bytecode.OpObject:
+			vm.push(value.NewObject())
+		case bytecode.OpPop:
+			vm.pop()
+		case bytecode.OpDup:
+			vm.push(vm.peek(0))
+		case bytecode.OpDup2:
+			vm.push(vm.peek(1))
+			vm.push(vm.peek(1))
+		case bytecode.OpTuck:
+			at := len(vm.stack) - 1 - readByte()
+			vm.push(vm.peek(0))
+			copy(vm.stack[at+1:], vm.stack[at:len(vm.stack)-1])
+			vm.stack[at] = vm.peek(0)
+
+		case bytecode.OpGetLocal:
+			vm.push(vm.stack[f.base+readByte()])
+		case bytecode.OpSetLocal:
+			vm.stack[f.base+readByte()] = vm.peek(0)
+		case bytecode.OpGetUpvalue:
+			uv := f.closure.upvalues[readByte()]
+			if uv.open {
+				vm.push(vm.stack[uv.slot])
+			} else {
+				vm.push(uv.closed)
+			}
+		case bytecode.OpSetUpvalue:
+			uv := f.closure.upvalues[readByte()]
+			if uv.open {
+				vm.stack[uv.slot] = vm.peek(0)
+			} else {
+				uv.closed = vm.peek(0)
+			}
+		case bytecode.OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case bytecode.OpGetGlobal:
+			name := chunk.Constants[readUint16()].(string)
+			v, ok := vm.globals[name]
+			if !ok {
+				return runtimeError(chunk, start, "%s is not defined", name)
+			}
+			vm.push(v)
+		case bytecode.OpSetGlobal:
+			name := chunk.Constants[readUint16()].(string)
+			if _, ok := vm.globals[name]; !ok {
+				return runtimeError(chunk, start, "%s is not defined", name)
+			}
+			vm.globals[name] = vm.peek(0)
+		case bytecode.OpDefineGlobal:
+			name := chunk.Constants[readUint16()].(string)
+			vm.globals[name] = vm.pop()
+
+		case bytecode.OpGetProp:
+			name := chunk.Constants[readUint16()].(string)
+			v, err := value.GetProp(vm.pop(), name)
+			if err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			vm.push(v)
+		case bytecode.OpSetProp:
+			name := chunk.Constants[readUint16()].(string)
+			v := vm.pop()
+			if err := value.SetProp(vm.pop(), name, v); err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			vm.push(v)
+		case bytecode.OpGetIndex:
+			key := vm.pop()
+			v, err := value.GetProp(vm.pop(), value.ToString(key))
+			if err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			vm.push(v)
+		case bytecode.OpSetIndex:
+			v := vm.pop()
+			key := vm.pop()
+			if err := value.SetProp(vm.pop(), value.ToString(key), v); err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			vm.push(v)
+		case bytecode.OpSuper:
+			name := chunk.Constants[readUint16()].(string)
+			super := f.closure.class.Super
+			method := super.FindMethod(name)
+			if method == nil {
+				return runtimeError(chunk, start, "class %s has no method %s", super.Name, name)
+			}
+			vm.push(method.Bind(vm.stack[f.base]))
+
+		case bytecode.OpEqual, bytecode.OpNotEqual, bytecode.OpInstanceof, bytecode.OpIn,
+			bytecode.OpGreater, bytecode.OpLess, bytecode.OpGreaterEqual, bytecode.OpLessEqual,
+			bytecode.OpAdd, bytecode.OpSub, bytecode.OpMul, bytecode.OpDiv, bytecode.OpMod, bytecode.OpPow,
+			bytecode.OpShl, bytecode.OpShr, bytecode.OpUShr, bytecode.OpBitAnd, bytecode.OpBitOr, bytecode.OpBitXor:
+			b := vm.pop()
+			v, err := value.BinaryOp(binaryOps[op], vm.pop(), b)
+			if err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			vm.push(v)
+		case bytecode.OpNot:
+			vm.push(!value.IsTruthy(vm.pop()))
+		case bytecode.OpNeg:
+			num, ok := vm.peek(0).(float64)
+			if !ok {
+				return runtimeError(chunk, start, "bad operand type for unary -: %s", value.TypeName(vm.peek(0)))
+			}
+			vm.stack[len(vm.stack)-1] = -num
+		case bytecode.OpBitNot:
+			num, ok := vm.peek(0).(float64)
+			if !ok {
+				return runtimeError(chunk, start, "bad operand type for unary ~: %s", value.TypeName(vm.peek(0)))
+			}
+			vm.stack[len(vm.stack)-1] = float64(^value.ToInt32(num))
+		case bytecode.OpTypeof:
+			vm.stack[len(vm.stack)-1] = value.TypeName(vm.peek(0))
+		case bytecode.OpInc, bytecode.OpDec:
+			num, ok := vm.peek(0).(float64)
+			if !ok {
+				return runtimeError(chunk, start, "bad operand type for %s: %s", updateSymbols[op], value.TypeName(vm.peek(0)))
+			}
+			if op == bytecode.OpInc {
+				vm.stack[len(vm.stack)-1] = num + 1
+			} else {
+				vm.stack[len(vm.stack)-1] = num - 1
+			}
+
+		case bytecode.OpJump:
+			offset := readUint16()
+			f.ip += offset
+		case bytecode.OpJumpIfFalse:
+			offset := readUint16()
+			if !value.IsTruthy(vm.peek(0)) {
+				f.ip += offset
+			}
+		case bytecode.OpJumpIfTrue:
+			offset := readUint16()
+			if value.IsTruthy(vm.peek(0)) {
+				f.ip += offset
+			}
+		case bytecode.OpLoop:
+			offset := readUint16()
+			f.ip -= offset
+		case bytecode.OpTry, bytecode.OpTryFinally:
+			offset := readUint16()
+			vm.handlers = append(vm.handlers, handler{
+				frame:   len(vm.frames) - 1,
+				ip:      f.ip + offset,
+				stack:   len(vm.stack),
+				finally: op == bytecode.OpTryFinally,
+			})
+		case bytecode.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case bytecode.OpThrow:
+			v := vm.pop()
+			t, ok := v.(*thrown)
+			if !ok {
+				t = &thrown{value: v, pos: chunk.Pos[start]}
+			}
+			if len(vm.handlers) == 0 {
+				return &RuntimeError{Pos: t.pos, Msg: fmt.Sprintf("uncaught exception: %s", value.ToString(t.value))}
+			}
+			h := vm.handlers[len(vm.handlers)-1]
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			vm.closeUpvalues(h.stack)
+			vm.stack = vm.stack[:h.stack]
+			if h.finally {
+				vm.push(t)
+			} else {
+				vm.push(t.value)
+			}
+			vm.frames = vm.frames[:h.frame+1]
+			f = &vm.frames[h.frame]
+			chunk = &f.closure.Fn.Chunk
+			f.ip = h.ip
+
+		case bytecode.OpCall:
+			if err := vm.callValue(readByte()); err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			f = &vm.frames[len(vm.frames)-1]
+			chunk = &f.closure.Fn.Chunk
+		case bytecode.OpNew:
+			if err := vm.construct(readByte()); err != nil {
+				return runtimeError(chunk, start, "%s", err)
+			}
+			f = &vm.frames[len(vm.frames)-1]
+			chunk = &f.closure.Fn.Chunk
+		case bytecode.OpClosure:
+			fn := chunk.Constants[readUint16()].(*bytecode.Function)
+			closure := &Closure{
+				Fn:       fn,
+				upvalues: make([]*upvalue, fn.UpvalueCount),
+			}
+			for i := range closure.upvalues {
+				isLocal := readByte()
+				index := readByte()
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(f.base + index)
+				} else {
+					closure.upvalues[i] = f.closure.upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case bytecode.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(f.base)
+			if f.construct {
+				result = vm.stack[f.base]
+			}
+			vm.stack = vm.stack[:f.base]
+			// The handlers of the try statements the function returns from
+			for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame == len(vm.frames)-1 {
+				vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+			vm.push(result)
+			f = &vm.frames[len(vm.frames)-1]
+			chunk = &f.closure.Fn.Chunk
+
+		case bytecode.OpClass:
+			vm.push(value.NewClass(chunk.Constants[readUint16()].(string)))
+		case bytecode.OpInherit:
+			super := vm.pop()
+			class := vm.peek(0).(*value.Class)
+			superClass, ok := super.(*value.Class)
+			if !ok {
+				return runtimeError(chunk, start, "class %s can't extend %s", class.Name, value.TypeName(super))
+			}
+			class.Super = superClass
+		case bytecode.OpMethod:
+			name := chunk.Constants[readUint16()].(string)
+			method := vm.pop().(*Closure)
+			class := vm.peek(0).(*value.Class)
+			method.class = class
+			method.method = true
+			class.Methods[name] = method
+		case bytecode.OpDefineProp:
+			name := chunk.Constants[readUint16()].(string)
+			v := vm.pop()
+			vm.peek(0).(*value.Object).Set(name, v)
+		case bytecode.OpDefineIndex:
+			v := vm.pop()
+			key := vm.pop()
+			vm.peek(0).(*value.Object).Set(value.ToString(key), v)
+		case bytecode.OpDefineMethod:
+			name := chunk.Constants[readUint16()].(string)
+			method := vm.pop().(*Closure)
+			method.method = true
+			vm.peek(0).(*value.Object).Set(name, method)
+
+		default:
+			return runtimeError(chunk, start, "unknown instruction %s", op)
+		}
+	}
+}
+
+func runtimeError(chunk *bytecode.Chunk, offset int, format string, args ...interface{}) error {
+	return &RuntimeError{
+		Pos: chunk.Pos[offset],
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (vm *VM) push(v Value) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() Value {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// callValue calls the callee placed on the stack below its arguments.
+func (vm *VM) callValue(argc int) error {
+	switch callee := vm.peek(argc).(type) {
+	case *Closure:
+		return vm.call(callee, argc, false)
+	case *BoundMethod:
+		vm.stack[len(vm.stack)-1-argc] = callee.Receiver
+		return vm.call(callee.Method, argc, false)
+	case *value.Builtin:
+		args := make([]Value, argc)
+		copy(args, vm.stack[len(vm.stack)-argc:])
+		v, err := callee.Fn(args)
+		if err != nil {
+			return fmt.Errorf("%s: %s", callee.Name, err)
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argc-1]
+		vm.push(v)
+		return nil
+	case *value.Class:
+		return fmt.Errorf("class %s can't be called without 'new'", callee.Name)
+	default:
+		return fmt.Errorf("%s is not a function", value.TypeName(callee))
+	}
+}
+
+// construct instantiates the class placed on the stack below the arguments
+// of its constructor.
+func (vm *VM) construct(argc int) error {
+	callee := vm.peek(argc)
+	class, ok := callee.(*value.Class)
+	if !ok {
+		return fmt.Errorf("%s is not a class", value.TypeName(callee))
+	}
+
+	vm.stack[len(vm.stack)-1-argc] = value.NewInstance(class)
+
+	if ctor := class.FindMethod("constructor"); ctor != nil {
+		return vm.call(ctor.(*Closure), argc, true)
+	}
+
+	vm.stack = vm.stack[:len(vm.stack)-argc]
+	return nil
+}
+
+// call pushes the frame of the closure. Missing arguments are set to null,
+// extra ones are dropped.
+func (vm *VM) call(closure *Closure, argc int, construct bool) error {
+	if len(vm.frames) >= maxFrames {
+		return fmt.Errorf("maximum call depth exceeded")
+	}
+
+	for ; argc < closure.Fn.Arity; argc++ {
+		vm.push(nil)
+	}
+	vm.stack = vm.stack[:len(vm.stack)-(argc-closure.Fn.Arity)]
+
+	vm.frames = append(vm.frames, frame{
+		closure:   closure,
+		base:      len(vm.stack) - closure.Fn.Arity - 1,
+		construct: construct,
+	})
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *upvalue {
+	var prev *upvalue
+	uv := vm.openUpvalues
+	for uv != nil && uv.slot > slot {
+		prev = uv
+		uv = uv.next
+	}
+	if uv != nil && uv.slot == slot {
+		return uv
+	}
+
+	created := &upvalue{
+		slot: slot,
+		open: true,
+		next: uv,
+	}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the variables living in the stack slots starting from
+// the last one into their upvalues.
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		uv := vm.openUpvalues
+		uv.closed = vm.stack[uv.slot]
+		uv.open = false
+		vm.openUpvalues = uv.next
+	}
+}
+
+// binaryOps maps the instructions of the binary operators to the operators
+// they apply.
+var binaryOps = map[bytecode.Op]ast.BinaryOp{
+	bytecode.OpEqual:        ast.EqBinaryOp,
+	bytecode.OpNotEqual:     ast.NeqBinaryOp,
+	bytecode.OpInstanceof:   ast.InstanceofBinaryOp,
+	bytecode.OpIn:           ast.InBinaryOp,
+	bytecode.OpGreater:      ast.GtBinaryOp,
+	bytecode.OpLess:         ast.LtBinaryOp,
+	bytecode.OpGreaterEqual: ast.GteBinaryOp,
+	bytecode.OpLessEqual:    ast.LteBinaryOp,
+	bytecode.OpAdd:          ast.AddBinaryOp,
+	bytecode.OpSub:          ast.SubBinaryOp,
+	bytecode.OpMul:          ast.MulBinaryOp,
+	bytecode.OpDiv:          ast.DivBinaryOp,
+	bytecode.OpMod:          ast.ModBinaryOp,
+	bytecode.OpPow:          ast.ExpBinaryOp,
+	bytecode.OpShl:          ast.ShlBinaryOp,
+	bytecode.OpShr:          ast.ShrBinaryOp,
+	bytecode.OpUShr:         ast.UShrBinaryOp,
+	bytecode.OpBitAnd:       ast.BitAndBinaryOp,
+	bytecode.OpBitOr:        ast.BitOrBinaryOp,
+	bytecode.OpBitXor:       ast.BitXorBinaryOp,
+}
+
+// updateSymbols holds the source form of the update operators for the error
+// messages.
+var updateSymbols = map[bytecode.Op]string{
+	bytecode.OpInc: "++",
+	bytecode.OpDec: "--",
+}
@@ -0,0 +1,609 @@
+package vm
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/ast"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/bytecode"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/interp"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/parser"
+	"github.com/alexey-medvedchikov/parser-from-scratch/internal/tokenizer"
+)
+
+// engines are the engines the tables run against, they must agree on the
+// output and the errors of every program.
+var engines = []struct {
+	name string
+	run  func(out io.Writer, program ast.Node) error
+}{
+	{"Interpreter", func(out io.Writer, program ast.Node) error {
+		return interp.NewInterpreter(out).Run(program)
+	}},
+	{"VM", func(out io.Writer, program ast.Node) error {
+		fn, err := bytecode.Compile(program)
+		if err != nil {
+			return err
+		}
+		return NewVM(out).Run(fn)
+	}},
+}
+
+func TestRun(t *testing.T) {
+	type test struct {
+		name    string
+		in      string
+		wantOut string
+	}
+	tests := []test{
+		{
+			name:    "arithmetic",
+			in:      `print(2 + 2 * 3, (2 + 2) * 3, 7 / 2, -4 - 1);`,
+			wantOut: "8 12 3.5 -5\n",
+		}, {
+			name:    "escapes",
+			in:      `print("a\tb", 'it\'s', "\u{48}\u0069");`,
+			wantOut: "a\tb it's Hi\n",
+		}, {
+			name:    "unicode identifiers",
+			in:      `let délai = 1, $el = 2; def Ωmega(_x) { return _x * 2; } print(Ωmega(délai + $el));`,
+			wantOut: "6\n",
+		}, {
+			name:    "numbers",
+			in:      `print(0xFF, 0b11 + 0o7, 1_000, 1.5 * 2, .5, 2.5e3);`,
+			wantOut: "255 10 1000 3 0.5 2500\n",
+		}, {
+			name:    "strings",
+			in:      `print("a" + "b", "n=" + 1, "abc".length, "abc"[2], "b" > "a");`,
+			wantOut: "ab n=1 3 c true\n",
+		}, {
+			name: "objects",
+			in: `
+let x = 1, k = "key";
+let o = {x, "y z": 2, 3: 4, [k + 1]: 5, x: x + 1, get() { return this.x; }};
+print(o, o.x, o["y z"], o[3], o.key1, o.missing);
+o.w = [o.get()];
+o["y z"] += 1;
+let get = o.get, p = {x: 10, get: o.get};
+print(o, get(), p.get(), {} == {}, o == o);
+o.self = o;
+print(o.self);
+`,
+			wantOut: "{x: 2, y z: 2, 3: 4, key1: 5, get: <function get>} 2 2 4 5 null\n" +
+				"{x: 2, y z: 3, 3: 4, key1: 5, get: <function get>, w: [2]} 2 2 false true\n" +
+				"{x: 2, y z: 3, 3: 4, key1: 5, get: <function get>, w: [2], self: {...}}\n",
+		}, {
+			name: "arrays",
+			in: `
+let a = [1, "two", [3], ,];
+print(a, a.length, a[1], a[2][0], a[3], a[9], a["1"], a["01"]);
+a[5] = 6;
+a[0] += 10;
+print(a, a.length, [], [,], a == a, [1] == [1]);
+a[6] = a;
+print(a);
+`,
+			wantOut: "[1, two, [3], null] 4 two 3 null null two null\n" +
+				"[11, two, [3], null, null, 6] 6 [] [null] true false\n" +
+				"[11, two, [3], null, null, 6, [...]]\n",
+		}, {
+			name:    "logical",
+			in:      `print(1 && 2, 0 && 2, null || "x", 0 || "", !1, 1 == 1, "1" == 1, 2 != 3);`,
+			wantOut: "2 0 x  false true false true\n",
+		}, {
+			name: "conditional",
+			in: `
+def sign(n) { return n > 0 ? 1 : n < 0 ? -1 : 0; }
+let x = 0, f = n => n ? "yes" : "no";
+true ? x = 1 : x = 2;
+print(sign(5), sign(-3), sign(0), f(0), f("a"), x, null ? 1 : 0 ? 2 : 3);
+`,
+			wantOut: "1 -1 0 no yes 1 3\n",
+		}, {
+			name: "scopes",
+			in: `
+let x = 1;
+{
+	let x = 2;
+	print(x);
+	x = 3;
+	{
+		let y = x * 2;
+		print(y);
+	}
+}
+print(x);
+`,
+			wantOut: "2\n6\n1\n",
+		}, {
+			name: "loops",
+			in: `
+let s = 0;
+for (let i = 0; i < 5; i += 1) {
+	s += i;
+}
+let j = 0;
+while (j < 3) j += 1;
+let k = 10;
+do {
+	k -= 1;
+} while (k > 100);
+def forever() {
+	for (;;) {
+		return "done";
+	}
+}
+print(s, j, k, forever());
+`,
+			wantOut: "10 3 9 done\n",
+		}, {
+			name: "break and continue",
+			in: `
+let s = "";
+outer: for (let i = 0; i < 4; i += 1) {
+	let j = 0;
+	while (true) {
+		j += 1;
+		if (j > i) continue outer;
+		if (i == 3) break outer;
+		if (j == 2) continue;
+		s += i + "" + j + " ";
+	}
+}
+let n = 0;
+do {
+	n += 1;
+	if (n < 3) continue;
+	break;
+} while (true);
+block: {
+	s += "a";
+	break block;
+	s += "b";
+}
+let g;
+for (let x = 0; ; x += 1) {
+	let y = x * 10;
+	g = () => y;
+	if (x == 2) break;
+}
+print(s, n, g());
+`,
+			wantOut: "11 21 a 3 20\n",
+		}, {
+			name: "try and throw",
+			in: `
+let log = "";
+def check(x) {
+	if (x < 0) throw "negative " + x;
+	return x;
+}
+def safe(x) {
+	try {
+		return check(x);
+	} catch (e) {
+		log += e + "; ";
+		return 0;
+	} finally {
+		log += "done " + x + "; ";
+	}
+}
+let sum = safe(1) + safe(-2);
+for (let i = 0; i < 3; i += 1) {
+	let j = i;
+	try {
+		let k = j;
+		if (k == 1) continue;
+		if (k == 2) break;
+	} finally {
+		log += "f" + j + " ";
+	}
+}
+let caught;
+try {
+	try {
+		throw 1;
+	} catch (e) {
+		throw e + 1;
+	} finally {
+		log += "inner";
+	}
+} catch (e) {
+	caught = () => e;
+}
+print(sum, caught(), log);
+`,
+			wantOut: "1 2 done 1; negative -2; done -2; f0 f1 f2 inner\n",
+		}, {
+			name: "switch",
+			in: `
+def name(n) {
+	let s = "";
+	switch (n) {
+	default:
+		s += "many";
+	case 0:
+		s += "none";
+		break;
+	case 1:
+		let one = "one";
+		return one;
+	case 1 + 1:
+	case 3:
+		s += "few";
+	}
+	return s;
+}
+let s = "";
+for (let i = 0; i < 5; i += 1) {
+	switch (i) {
+	case 0:
+	case 2:
+	case 4:
+		continue;
+	}
+	s += name(i) + " ";
+}
+out: switch (1) {
+case 1:
+	for (;;) {
+		switch (0) {
+		case 0:
+			break out;
+		}
+	}
+}
+print(name(0), name(2), name(4), s);
+`,
+			wantOut: "none few manynone one few \n",
+		}, {
+			name: "arithmetic and bitwise operators",
+			in: `
+print(7 % 3, -7 % 3, 5.5 % 2, 2 ** 3 ** 2, -2 ** 2, (-2) ** 2, 2 ** -1);
+print(5 & 3, 5 | 3, 5 ^ 3, ~5, ~-1, 1 | 6 ^ 3 & 5);
+print(1 << 31, -16 >> 2, -16 >>> 28, 1 << 33, 2 ** 32 | 0, 4294967297 | 0, -1.9 | 0);
+let x = 10;
+x %= 4; x **= 3; x <<= 2; x >>= 1; x >>>= 1; x &= 13; x |= 2; x ^= 1;
+print(x);
+`,
+			wantOut: "1 -1 1.5 512 -4 4 0.5\n1 7 6 -6 0 7\n-2147483648 -4 15 2 0 1 -1\n11\n",
+		}, {
+			name: "update expressions",
+			in: `
+let i = 0, a = [5], o = {n: 1}, s = "";
+for (let j = 0; j < 3; j++) {
+	s += j;
+}
+print(i++, i, ++i, i--, --i, i);
+print(a[0]++, a[0], --a[0], o.n++, o.n, ++o.n);
+let k = 0;
+a[k++] += 10;
+print(a[0], k, s);
+def counter() {
+	let c = 0;
+	return () => ++c;
+}
+let next = counter();
+next();
+print(next());
+`,
+			wantOut: "0 1 2 2 0 0\n5 6 5 1 2 3\n15 1 012\n2\n",
+		}, {
+			name: "type tests",
+			in: `
+class A {
+	def m() {}
+}
+class B extends A {}
+let b = new B(), o = {k: null}, arr = [1];
+b.x = 1;
+print(b instanceof A, b instanceof B, new A() instanceof B, o instanceof A, 1 instanceof A);
+print("m" in b, "x" in b, "y" in b, "k" in o, "z" in o, 0 in arr, 1 in arr, "length" in arr);
+print(typeof 1, typeof "s", typeof null, typeof true, typeof print, typeof A, typeof b, typeof arr, typeof o, typeof (() => 1));
+print(1 === 1, 1 !== 1, "1" === 1, null !== 0);
+`,
+			wantOut: "true true false false false\n" +
+				"true true false true false true false true\n" +
+				"number string null boolean function class object array object function\n" +
+				"true false false true\n",
+		}, {
+			name: "compound assignment order",
+			in: `
+let x = 1, o = {n: 1};
+x += (x = 10);
+o.n *= (o.n = 10);
+print(x, o.n);
+`,
+			wantOut: "11 10\n",
+		}, {
+			name: "function equality",
+			in: `
+class A { def m() { return this; } }
+let a = new A(), b = new A(), m = a.m;
+let i = 0, fs = [0, 0];
+while (i < 2) fs[i] = () => 1, i += 1;
+print(a.m == a.m, m == a.m, a.m == b.m, fs[0] == fs[0], fs[0] == fs[1]);
+`,
+			wantOut: "true true false true false\n",
+		}, {
+			name: "recursion",
+			in: `
+def fib(n) {
+	if (n < 2) {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+print(fib(15));
+`,
+			wantOut: "610\n",
+		}, {
+			name: "closures",
+			in: `
+def counter() {
+	let n = 0;
+	def inc() {
+		n += 1;
+		return n;
+	}
+	return inc;
+}
+let a = counter(), b = counter();
+a(); a();
+print(a(), b());
+`,
+			wantOut: "3 1\n",
+		}, {
+			name: "shared upvalues",
+			in: `
+def pair() {
+	let v = 0;
+	let p;
+	{
+		def get() { return v; }
+		def set(x) { v = x; }
+		p = new Pair(get, set);
+	}
+	return p;
+}
+class Pair {
+	def constructor(a, b) {
+		this.a = a;
+		this.b = b;
+	}
+}
+let p = pair();
+p.b(42);
+print(p.a());
+`,
+			wantOut: "42\n",
+		}, {
+			name: "local functions",
+			in: `
+{
+	def fact(n) {
+		if (n <= 1) return 1;
+		return n * fact(n - 1);
+	}
+	print(fact(5));
+}
+`,
+			wantOut: "120\n",
+		}, {
+			name: "function expressions",
+			in: `
+def apply(f, x) { return f(x); }
+let inc = x => x + 1, add = (a, b) => { return a + b; };
+let fact = def f(n) { if (n < 2) { return 1; } return n * f(n - 1); };
+print(apply(inc, 1), add(2, 3), (() => {})(), apply(def (x) { return x * 2; }, 4), fact(5));
+let o = {x: 1, get() { return () => this.x; }};
+let make = n => () => n += 1, next = make(10);
+next();
+print(o.get()(), next(), inc, def g() {});
+`,
+			wantOut: "2 5 null 8 120\n1 12 <function> <function g>\n",
+		}, {
+			name: "classes",
+			in: `
+class Point {
+	def constructor(x, y) {
+		this.x = x;
+		this.y = y;
+	}
+
+	def calc() {
+		return this.x + this.y;
+	}
+}
+
+class Point3D extends Point {
+	def constructor(x, y, z) {
+		super(x, y);
+		this.z = z;
+	}
+
+	def calc() {
+		return super() + this.z;
+	}
+}
+
+let p = new Point3D(10, 20, 30);
+let calc = p.calc;
+p.x = 100;
+p["y"] *= 2;
+print(p.calc(), calc(), p.y, p, p.calc == p.calc);
+`,
+			wantOut: "170 170 40 <Point3D instance> true\n",
+		}, {
+			name: "method closures",
+			in: `
+class Counter {
+	def constructor() {
+		this.n = 0;
+	}
+
+	def incrementer() {
+		def inc() {
+			this.n += 1;
+			return this.n;
+		}
+		return inc;
+	}
+}
+let c = new Counter();
+let inc = c.incrementer();
+inc();
+print(inc(), c.n);
+`,
+			wantOut: "2 2\n",
+		},
+	}
+
+	for _, tc := range tests {
+		for _, e := range engines {
+			t.Run(tc.name+"/"+e.name, func(t *testing.T) {
+				var out bytes.Buffer
+				err := e.run(&out, mustParse(t, tc.in))
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantOut, out.String())
+			})
+		}
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	type test struct {
+		in      string
+		wantErr string
+	}
+	tests := []test{
+		{
+			in:      `print(x);`,
+			wantErr: `1:7: x is not defined`,
+		}, {
+			in:      `x = 1;`,
+			wantErr: `1:1: x is not defined`,
+		}, {
+			in:      "let f = 1;\nf();",
+			wantErr: `2:1: number is not a function`,
+		}, {
+			in:      `let g = def f() {}; f();`,
+			wantErr: `1:21: f is not defined`,
+		}, {
+			in:      `1 - "a";`,
+			wantErr: `1:1: bad operand types for -: number and string`,
+		}, {
+			in:      `null.x;`,
+			wantErr: `1:1: can't read property "x" of null`,
+		}, {
+			in:      `let a = [1]; a.x = 2;`,
+			wantErr: `1:14: can't set property "x" of array`,
+		}, {
+			in:      `let a = []; a[16777216] = 1;`,
+			wantErr: `1:13: array index 16777216 out of range`,
+		}, {
+			in:      `return 1;`,
+			wantErr: `1:1: return outside of function`,
+		}, {
+			in:      `def f() { super(); } f();`,
+			wantErr: `1:11: 'super' outside of method`,
+		}, {
+			in:      `({m() { return super(); }}).m();`,
+			wantErr: `1:16: 'super' outside of method`,
+		}, {
+			in:      `class A { def m() { return () => super(); } } new A().m()();`,
+			wantErr: `1:34: 'super' outside of method`,
+		}, {
+			in:      `class A { def m() { return super(); } } new A().m();`,
+			wantErr: `1:28: class A has no parent class`,
+		}, {
+			in:      `class A {} A();`,
+			wantErr: `1:12: class A can't be called without 'new'`,
+		}, {
+			in:      `def f() { return f(); } f();`,
+			wantErr: `1:18: maximum call depth exceeded`,
+		}, {
+			in:      `1 instanceof 1;`,
+			wantErr: `1:1: number is not a class`,
+		}, {
+			in:      `"a" in 1;`,
+			wantErr: `1:1: can't search for property "a" in number`,
+		}, {
+			in:      `let s = "a"; s++;`,
+			wantErr: `1:14: bad operand type for ++: string`,
+		}, {
+			in:      `"a" % 2;`,
+			wantErr: `1:1: bad operand types for %: string and number`,
+		}, {
+			in:      `~"a";`,
+			wantErr: `1:1: bad operand type for unary ~: string`,
+		}, {
+			in:      `def f() { throw "boom"; } try { f(); } finally {}`,
+			wantErr: `1:11: uncaught exception: boom`,
+		}, {
+			in:      `class A {} class B extends A { def m() { return super(); } } new B().m();`,
+			wantErr: `1:49: class A has no method m`,
+		},
+	}
+
+	for _, tc := range tests {
+		for _, e := range engines {
+			t.Run(tc.in+"/"+e.name, func(t *testing.T) {
+				err := e.run(io.Discard, mustParse(t, tc.in))
+				assert.EqualError(t, err, tc.wantErr)
+			})
+		}
+	}
+}
+
+const benchProgram = `
+def fib(n) {
+	if (n < 2) {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+let s = 0;
+for (let i = 0; i < 1000; i += 1) {
+	s += i;
+}
+fib(18);
+`
+
+func BenchmarkVM(b *testing.B) {
+	prog := mustParse(b, benchProgram)
+	fn, err := bytecode.Compile(prog)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	vm := NewVM(io.Discard)
+	for i := 0; i < b.N; i++ {
+		if err := vm.Run(fn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpreter(b *testing.B) {
+	prog := mustParse(b, benchProgram)
+
+	in := interp.NewInterpreter(io.Discard)
+	for i := 0; i < b.N; i++ {
+		if err := in.Run(prog); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func mustParse(t testing.TB, in string) ast.Node {
+	tok := tokenizer.NewScanner(in)
+	node, err := parser.NewParser(tok, ast.Builder{}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}